@@ -0,0 +1,233 @@
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewAdmin creates an Admin handler over the provided db with the provided
+// opts
+func NewAdmin(db DB, opts ...AdminOpt) *Admin {
+	a := &Admin{
+		db:          db,
+		collections: map[string]Reloadable{},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Admin is an http.Handler that lets operators inspect and control the
+// collections that are registered to it. it serves the following routes,
+// relative to the path it is mounted on:
+//
+//	GET  /kinds                        lists the registered kinds, their counts and stats
+//	GET  /kinds/{kind}                 returns the count and stats of a kind
+//	GET  /kinds/{kind}/{index}/{value} returns the item of the kind by the index
+//	POST /kinds/{kind}/invalidate      reloads the kind from its origin source
+//	GET  /tags?prefix={prefix}         lists the tags in the db and their key counts
+type Admin struct {
+	db   DB
+	auth func(r *http.Request) error
+
+	mu          sync.RWMutex
+	collections map[string]Reloadable
+}
+
+// AdminOpt is an option for instrumenting the Admin handler
+type AdminOpt func(*Admin)
+
+// AdminCollections registers the provided collections on the Admin handler
+func AdminCollections(collections ...Reloadable) AdminOpt {
+	return func(a *Admin) {
+		a.Register(collections...)
+	}
+}
+
+// AdminAuth sets an auth hook that is called before serving any request.
+// returning an error rejects the request with 403. the hook can inspect the
+// request's method in order to authorize only mutating requests
+func AdminAuth(fn func(r *http.Request) error) AdminOpt {
+	return func(a *Admin) {
+		a.auth = fn
+	}
+}
+
+// Register adds the provided collections to the Admin handler
+func (a *Admin) Register(collections ...Reloadable) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, col := range collections {
+		a.collections[col.Kind()] = col
+	}
+}
+
+// KindInfo is the representation of a registered kind served by Admin
+type KindInfo struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+	Stats Stats  `json:"stats"`
+}
+
+// TagInfo is the representation of a tag served by Admin
+type TagInfo struct {
+	Tag  string `json:"tag"`
+	Keys int    `json:"keys"`
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.auth != nil {
+		if err := a.auth(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	path := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i := range path {
+		segment, err := url.PathUnescape(path[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		path[i] = segment
+	}
+
+	switch {
+	case len(path) == 1 && path[0] == "kinds":
+		a.onlyGet(w, r, a.listKinds)
+	case len(path) == 1 && path[0] == "tags":
+		a.onlyGet(w, r, a.listTags)
+	case len(path) == 2 && path[0] == "kinds":
+		a.onlyGet(w, r, func(w http.ResponseWriter, r *http.Request) {
+			a.getKind(w, path[1])
+		})
+	case len(path) == 3 && path[0] == "kinds" && path[2] == "invalidate":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		a.invalidate(w, r, path[1])
+	case len(path) == 4 && path[0] == "kinds":
+		a.onlyGet(w, r, func(w http.ResponseWriter, r *http.Request) {
+			a.getItem(w, path[1], path[2], path[3])
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *Admin) onlyGet(w http.ResponseWriter, r *http.Request, handle http.HandlerFunc) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	handle(w, r)
+}
+
+func (a *Admin) collection(kind string) (col Reloadable, ok bool) {
+	a.mu.RLock()
+	col, ok = a.collections[kind]
+	a.mu.RUnlock()
+
+	return
+}
+
+func (a *Admin) listKinds(w http.ResponseWriter, _ *http.Request) {
+	a.mu.RLock()
+	res := make([]KindInfo, 0, len(a.collections))
+	for _, col := range a.collections {
+		res = append(res, kindInfo(col))
+	}
+	a.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Kind < res[j].Kind })
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (a *Admin) getKind(w http.ResponseWriter, kind string) {
+	col, ok := a.collection(kind)
+	if !ok {
+		http.Error(w, "unknown kind", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, kindInfo(col))
+}
+
+func (a *Admin) getItem(w http.ResponseWriter, kind, index, val string) {
+	col, ok := a.collection(kind)
+	if !ok {
+		http.Error(w, "unknown kind", http.StatusNotFound)
+		return
+	}
+
+	item, ok := col.lookup(index, val)
+	if !ok {
+		http.Error(w, "item not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (a *Admin) invalidate(w http.ResponseWriter, r *http.Request, kind string) {
+	col, ok := a.collection(kind)
+	if !ok {
+		http.Error(w, "unknown kind", http.StatusNotFound)
+		return
+	}
+
+	if _, err := col.Reload(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, kindInfo(col))
+}
+
+func (a *Admin) listTags(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	res := []TagInfo{}
+	a.db.Tags(func(tag string, keys int) bool {
+		if strings.HasPrefix(tag, prefix) {
+			res = append(res, TagInfo{tag, keys})
+		}
+
+		return true
+	})
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Tag < res[j].Tag })
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func kindInfo(col Reloadable) KindInfo {
+	return KindInfo{
+		Kind:  col.Kind(),
+		Count: col.count(),
+		Stats: col.Stats(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,150 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type book struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
+
+func TestAdmin(t *testing.T) {
+	db := NewDB()
+
+	books := []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "The Hitchhiker's Guide to the Galaxy", "Douglas Adams"},
+		{"3", "The Restaurant at the End of the Universe", "Douglas Adams"},
+	}
+
+	bookCol := NewCollection[*book](db, "books",
+		Extractor(func(load func(in ...*book)) { load(books...) }),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		AdditionalKey("title", func(b *book, val func(string)) { val(b.Title) }),
+		AdditionalKey("author", func(b *book, val func(string)) { val(b.Author) }),
+	)
+	bookCol.Invalidate()
+
+	admin := NewAdmin(db,
+		AdminCollections(bookCol),
+		AdminAuth(func(r *http.Request) error {
+			if r.Method != http.MethodGet && r.Header.Get("Authorization") != "secret" {
+				return fmt.Errorf("unauthorized")
+			}
+
+			return nil
+		}),
+	)
+
+	srv := httptest.NewServer(admin)
+	defer srv.Close()
+
+	do := func(method, path string, auth string, dst any) int {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		req.Header.Set("Authorization", auth)
+
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer res.Body.Close()
+
+		if dst != nil && res.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(dst))
+		}
+
+		return res.StatusCode
+	}
+
+	var kinds []KindInfo
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/kinds", "", &kinds))
+	if assert.Len(t, kinds, 1) {
+		assert.Equal(t, "books", kinds[0].Kind)
+		assert.Equal(t, 3, kinds[0].Count)
+		assert.Equal(t, 3, kinds[0].Stats.Items)
+		assert.Equal(t, 1, kinds[0].Stats.Reloads)
+	}
+
+	var item book
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/kinds/books/id/1", "", &item))
+	assert.Equal(t, *books[0], item)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/kinds/books/title/The%20Hitchhiker's%20Guide%20to%20the%20Galaxy", "", &item))
+	assert.Equal(t, *books[1], item)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/kinds/books/id/4", "", nil))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/kinds/authors/id/1", "", nil))
+
+	var tags []TagInfo
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/tags?prefix=books{author:", "", &tags))
+	assert.Equal(t, []TagInfo{
+		{"books{author:Douglas Adams}", 2},
+		{"books{author:Frank Herbert}", 1},
+	}, tags)
+
+	books = append(books, &book{"4", "Mostly Harmless", "Douglas Adams"})
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/kinds/books/invalidate", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/kinds/books/invalidate", "", nil))
+
+	var kind KindInfo
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/kinds/books/invalidate", "secret", &kind))
+	assert.Equal(t, 4, kind.Count)
+	assert.Equal(t, 2, kind.Stats.Reloads)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/kinds/books/id/4", "", &item))
+	assert.Equal(t, *books[3], item)
+}
+
+func TestAdminReadThrough(t *testing.T) {
+	db := NewDB()
+
+	var calls int
+	bookCol := NewCollection[*book](db, "books",
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		ReadThrough(func(ctx context.Context, index, val string) (*book, bool, error) {
+			calls++
+			return &book{val, "Dune", "Frank Herbert"}, val == "1", nil
+		}, time.Minute),
+	)
+
+	srv := httptest.NewServer(NewAdmin(db, AdminCollections(bookCol)))
+	defer srv.Close()
+
+	get := func(path string) int {
+		res, err := http.Get(srv.URL + path)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer res.Body.Close()
+
+		return res.StatusCode
+	}
+
+	// items are served only if they're loaded, without loading them
+	assert.Equal(t, http.StatusNotFound, get("/kinds/books/id/1"))
+	assert.Equal(t, http.StatusNotFound, get("/kinds/books/id/2"))
+	assert.Equal(t, 0, calls)
+
+	_, ok := db.Get("books{!id:2}")
+	assert.False(t, ok)
+
+	_, ok = bookCol.GetBy("id")("1")
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusOK, get("/kinds/books/id/1"))
+	assert.Equal(t, 1, calls)
+}
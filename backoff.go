@@ -0,0 +1,64 @@
+package inventory
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff defines the exponentially growing delays between attempts
+type Backoff struct {
+	// Initial is the delay after the first attempt
+	Initial time.Duration
+
+	// Max caps the delay
+	Max time.Duration
+
+	// Multiplier is the growth factor of the delay between attempts
+	Multiplier float64
+
+	// Jitter is the fraction of the delay that is randomized, between 0 and 1
+	Jitter float64
+}
+
+// DefaultBackoff starts at 100ms and doubles the delay up to 10s, randomizing
+// 20% of it
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        10 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay after the provided attempt, starting at 1
+func (b Backoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(max(attempt-1, 0)))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		delay -= delay * b.Jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// sleep waits for the provided duration or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,415 @@
+package inventory
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// bitmap is a compressed set of ordinals in the style of roaring bitmaps. the
+// ordinals are split to containers by their high 16 bits, and every container
+// holds the low 16 bits either in a sorted array, if it is sparse, or in a
+// bitset
+type bitmap struct {
+	keys       []uint16
+	containers []*container
+}
+
+// arrayMax is the max cardinality of an array container
+const arrayMax = 4096
+
+type container struct {
+	array []uint16
+	bits  []uint64
+	n     int
+}
+
+// add adds the provided ordinal, which must be greater than all the ordinals
+// in the bitmap
+func (b *bitmap) add(x uint32) {
+	hi, lo := uint16(x>>16), uint16(x)
+
+	if len(b.keys) == 0 || b.keys[len(b.keys)-1] != hi {
+		b.keys = append(b.keys, hi)
+		b.containers = append(b.containers, &container{})
+	}
+
+	c := b.containers[len(b.containers)-1]
+	if c.bits == nil && c.n == arrayMax {
+		c.bits = c.bitset()
+		c.array = nil
+	}
+
+	if c.bits != nil {
+		c.bits[lo/64] |= 1 << (lo % 64)
+	} else {
+		c.array = append(c.array, lo)
+	}
+
+	c.n++
+}
+
+// bitset returns the container as a bitset
+func (c *container) bitset() []uint64 {
+	if c.bits != nil {
+		return c.bits
+	}
+
+	res := make([]uint64, 1024)
+	for _, lo := range c.array {
+		res[lo/64] |= 1 << (lo % 64)
+	}
+
+	return res
+}
+
+// fromBitset creates a container from the provided bitset, which is
+// converted to an array if it is sparse
+func fromBitset(set []uint64) *container {
+	n := 0
+	for _, w := range set {
+		n += bits.OnesCount64(w)
+	}
+
+	if n > arrayMax {
+		return &container{bits: set, n: n}
+	}
+
+	c := &container{array: make([]uint16, 0, n), n: n}
+	for i, w := range set {
+		for w != 0 {
+			c.array = append(c.array, uint16(i*64+bits.TrailingZeros64(w)))
+			w &= w - 1
+		}
+	}
+
+	return c
+}
+
+// combine combines the containers of the provided bitmaps by the provided
+// bitwise op. left and right tell whether containers that are only in one of
+// the bitmaps are kept
+func (b *bitmap) combine(o *bitmap, left, right bool, op func(x, y uint64) uint64) *bitmap {
+	res := &bitmap{}
+	keep := func(key uint16, c *container) {
+		if c.n > 0 {
+			res.keys = append(res.keys, key)
+			res.containers = append(res.containers, c)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(b.keys) || j < len(o.keys) {
+		switch {
+		case j == len(o.keys) || i < len(b.keys) && b.keys[i] < o.keys[j]:
+			if left {
+				keep(b.keys[i], b.containers[i])
+			}
+			i++
+		case i == len(b.keys) || o.keys[j] < b.keys[i]:
+			if right {
+				keep(o.keys[j], o.containers[j])
+			}
+			j++
+		default:
+			x, y := b.containers[i].bitset(), o.containers[j].bitset()
+			set := make([]uint64, 1024)
+			for k := range set {
+				set[k] = op(x[k], y[k])
+			}
+
+			keep(b.keys[i], fromBitset(set))
+			i++
+			j++
+		}
+	}
+
+	return res
+}
+
+func (b *bitmap) and(o *bitmap) *bitmap {
+	return b.combine(o, false, false, func(x, y uint64) uint64 { return x & y })
+}
+
+func (b *bitmap) or(o *bitmap) *bitmap {
+	return b.combine(o, true, true, func(x, y uint64) uint64 { return x | y })
+}
+
+func (b *bitmap) andNot(o *bitmap) *bitmap {
+	return b.combine(o, true, false, func(x, y uint64) uint64 { return x &^ y })
+}
+
+func (b *bitmap) cardinality() (n int) {
+	for _, c := range b.containers {
+		n += c.n
+	}
+
+	return
+}
+
+// each calls fn with the ordinals of the bitmap in ascending order
+func (b *bitmap) each(fn func(x uint32)) {
+	for i, c := range b.containers {
+		hi := uint32(b.keys[i]) << 16
+		if c.bits == nil {
+			for _, lo := range c.array {
+				fn(hi | uint32(lo))
+			}
+
+			continue
+		}
+
+		for k, w := range c.bits {
+			for w != 0 {
+				fn(hi | uint32(k*64+bits.TrailingZeros64(w)))
+				w &= w - 1
+			}
+		}
+	}
+}
+
+// bitmapIndex is an index of the collection over low cardinality values that
+// is held in bitmaps of the ordinals of the items instead of tags
+type bitmapIndex[T any] struct {
+	name string
+	ref  indexFn[T]
+}
+
+// bitmaps are the bitmap indexes of a collection. the items are given dense
+// ordinals by the order of their keys, and the bitmaps are built once per
+// version of the collection
+type bitmaps[T any] struct {
+	indexes []bitmapIndex[T]
+
+	mu      sync.Mutex
+	built   bool
+	version uint64
+	keys    []string
+	all     *bitmap
+	values  map[string]map[string]*bitmap
+}
+
+// BitmapIndex adds an index of the collection for low cardinality values, like
+// statuses or regions. it is held in compressed bitmaps instead of tags, so it
+// takes less memory and is faster to combine by a Predicate and to count
+func BitmapIndex[T any](name string, value indexFn[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.bitmaps.indexes = append(c.bitmaps.indexes, bitmapIndex[T]{name, value})
+	}
+}
+
+func (c *Collection[T]) bitmapIndex(name string) (bitmapIndex[T], bool) {
+	i := slices.IndexFunc(c.bitmaps.indexes, func(idx bitmapIndex[T]) bool { return idx.name == name })
+	if i < 0 {
+		return bitmapIndex[T]{}, false
+	}
+
+	return c.bitmaps.indexes[i], true
+}
+
+// build builds the bitmaps of the items of the collection in the provided
+// viewer, if the collection has changed since they were last built. it
+// returns the values of the bitmap indexes and the keys of the ordinals
+func (b *bitmaps[T]) build(viewer DBViewer, kind string) (values map[string]map[string]*bitmap, all *bitmap, keys []string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	version := viewer.TagVersion(itemsTag(kind))
+	if b.built && b.version == version {
+		return b.values, b.all, b.keys, nil
+	}
+
+	viewer.Iter(itemsTag(kind), func(key string, _ func() (any, bool)) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+
+	all = &bitmap{}
+	values = map[string]map[string]*bitmap{}
+	for _, idx := range b.indexes {
+		values[idx.name] = map[string]*bitmap{}
+	}
+
+	for o, key := range keys {
+		i, _ := viewer.Get(key)
+		t, ok := i.(T)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+		}
+
+		all.add(uint32(o))
+
+		for _, idx := range b.indexes {
+			idx.ref(t, func(v string) {
+				bm, ok := values[idx.name][v]
+				if !ok {
+					bm = &bitmap{}
+					values[idx.name][v] = bm
+				}
+
+				// an item may emit the same value more than once
+				if n := len(bm.keys); n > 0 && bm.keys[n-1] == uint16(o>>16) && bm.containers[n-1].last() == uint16(o) {
+					return
+				}
+
+				bm.add(uint32(o))
+			})
+		}
+	}
+
+	b.built, b.version = true, version
+	b.values, b.all, b.keys = values, all, keys
+
+	return
+}
+
+// last returns the greatest ordinal of a non-empty container
+func (c *container) last() uint16 {
+	if c.bits == nil {
+		return c.array[len(c.array)-1]
+	}
+
+	for k := len(c.bits) - 1; k >= 0; k-- {
+		if w := c.bits[k]; w != 0 {
+			return uint16(k*64 + 63 - bits.LeadingZeros64(w))
+		}
+	}
+
+	return 0
+}
+
+// bitmapOnly reports whether all the indexes of the provided predicate are
+// bitmap indexes, so it can be evaluated by bitmaps alone
+func (c *Collection[T]) bitmapOnly(pred Predicate) bool {
+	switch pred.op {
+	case "where":
+		_, ok := c.bitmapIndex(pred.index)
+		return ok
+	case "and", "or", "not":
+		for _, child := range pred.children {
+			if !c.bitmapOnly(child) {
+				return false
+			}
+		}
+
+		return len(pred.children) > 0
+	default:
+		return false
+	}
+}
+
+// planBitmap evaluates the provided predicate by the bitmap indexes into the
+// keys of the items it selects
+func (c *Collection[T]) planBitmap(viewer DBViewer, pred Predicate) (*queryPlan, error) {
+	values, all, keys, err := c.bitmaps.build(viewer, c.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.evalBitmap(pred, values, all)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = map[string]struct{}{}
+	p.bits.each(func(o uint32) { p.keys[keys[o]] = struct{}{} })
+
+	return p, nil
+}
+
+func (c *Collection[T]) evalBitmap(pred Predicate, values map[string]map[string]*bitmap, all *bitmap) (p *queryPlan, err error) {
+	p = &queryPlan{bits: &bitmap{}}
+
+	switch pred.op {
+	case "where":
+		vals := values[pred.index]
+		p.desc = fmt.Sprintf("bitmap %s %s", pred.index, pred.match)
+
+		if pred.match.op == "between" {
+			for v, bm := range vals {
+				if v >= pred.match.vals[0] && v <= pred.match.vals[1] {
+					p.bits = p.bits.or(bm)
+				}
+			}
+
+			return
+		}
+
+		for _, v := range pred.match.vals {
+			if bm, ok := vals[v]; ok {
+				p.bits = p.bits.or(bm)
+			}
+		}
+	case "or":
+		p.desc = "bitmap or"
+		for _, child := range pred.children {
+			var cp *queryPlan
+			if cp, err = c.evalBitmap(child, values, all); err != nil {
+				return
+			}
+
+			p.children = append(p.children, cp)
+			p.bits = p.bits.or(cp.bits)
+		}
+	case "and":
+		p.desc = "bitmap and"
+		p.bits = all
+		for _, child := range pred.children {
+			var cp *queryPlan
+			if cp, err = c.evalBitmap(child, values, all); err != nil {
+				return
+			}
+
+			p.children = append(p.children, cp)
+			p.bits = p.bits.and(cp.bits)
+		}
+	case "not":
+		var cp *queryPlan
+		if cp, err = c.evalBitmap(pred.children[0], values, all); err != nil {
+			return
+		}
+
+		p.desc = "bitmap not"
+		p.children = []*queryPlan{cp}
+		p.bits = all.andNot(cp.bits)
+	default:
+		err = fmt.Errorf("invalid predicate")
+	}
+
+	return
+}
+
+// countBitmap returns the number of items with the provided value of the
+// provided bitmap index
+func (c *Collection[T]) countBitmap(index, val string) (n int) {
+	_ = c.db.View(func(viewer DBViewer) error {
+		values, _, _, err := c.bitmaps.build(viewer, c.kind)
+		if bm, ok := values[index][val]; ok {
+			n = bm.cardinality()
+		}
+
+		return err
+	})
+
+	return
+}
+
+// countBitmapBy returns the number of items of every value of the provided
+// bitmap index
+func (c *Collection[T]) countBitmapBy(index string) map[string]int {
+	res := map[string]int{}
+
+	_ = c.db.View(func(viewer DBViewer) error {
+		values, _, _, err := c.bitmaps.build(viewer, c.kind)
+		for v, bm := range values[index] {
+			res[v] = bm.cardinality()
+		}
+
+		return err
+	})
+
+	return res
+}
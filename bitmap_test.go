@@ -0,0 +1,127 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bitmap(t *testing.T) {
+	build := func(n int, pred func(i int) bool) (*bitmap, map[uint32]bool) {
+		bm, set := &bitmap{}, map[uint32]bool{}
+		for i := 0; i < n; i++ {
+			if pred(i) {
+				bm.add(uint32(i))
+				set[uint32(i)] = true
+			}
+		}
+
+		return bm, set
+	}
+
+	// dense and sparse containers over a few high keys
+	evens, evenSet := build(200000, func(i int) bool { return i%2 == 0 })
+	sparse, sparseSet := build(200000, func(i int) bool { return i%1000 == 0 || i > 150000 })
+
+	check := func(bm *bitmap, expected func(x uint32) bool) {
+		n := 0
+		prev := -1
+		bm.each(func(x uint32) {
+			assert.True(t, expected(x), x)
+			assert.Greater(t, int(x), prev)
+			prev = int(x)
+			n++
+		})
+		assert.Equal(t, n, bm.cardinality())
+
+		for i := uint32(0); i < 200000; i++ {
+			if expected(i) {
+				n--
+			}
+		}
+		assert.Zero(t, n)
+	}
+
+	check(evens, func(x uint32) bool { return evenSet[x] })
+	check(evens.and(sparse), func(x uint32) bool { return evenSet[x] && sparseSet[x] })
+	check(evens.or(sparse), func(x uint32) bool { return evenSet[x] || sparseSet[x] })
+	check(evens.andNot(sparse), func(x uint32) bool { return evenSet[x] && !sparseSet[x] })
+	check(sparse.andNot(evens), func(x uint32) bool { return sparseSet[x] && !evenSet[x] })
+}
+
+type account struct {
+	ID, Status, Region, Plan string
+}
+
+func TestBitmapIndex(t *testing.T) {
+	var accounts []*account
+	statuses, regions, plans := []string{"active", "suspended", "trial"}, []string{"eu", "us"}, []string{"free", "pro", "team", "enterprise"}
+	for i := 0; i < 120; i++ {
+		accounts = append(accounts, &account{fmt.Sprintf("%03d", i), statuses[i%3], regions[i%2], plans[i%4]})
+	}
+
+	col := NewCollection[*account](NewDB(), "accounts",
+		Source(func(ctx context.Context, load func(in ...*account)) error {
+			load(accounts...)
+			return nil
+		}),
+		PrimaryKey("id", func(a *account, val func(string)) { val(a.ID) }),
+		BitmapIndex("status", func(a *account, val func(string)) { val(a.Status) }),
+		BitmapIndex("region", func(a *account, val func(string)) { val(a.Region) }),
+		BitmapIndex("plan", func(a *account, val func(string)) { val(a.Plan); val(a.Plan) }),
+	)
+	col.MapBy("tier", func(a *account, val func(string)) {
+		if a.Plan == "enterprise" {
+			val("top")
+		}
+	})
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 40, col.Count("status", "active"))
+	assert.Equal(t, 30, col.Count("plan", "pro"))
+	assert.Equal(t, map[string]int{"eu": 60, "us": 60}, col.CountBy("region"))
+
+	count := func(pred Predicate) int {
+		n, err := col.Select(pred).Count()
+		assert.NoError(t, err)
+		return n
+	}
+
+	// i%3 == 0 and i%2 == 0
+	assert.Equal(t, 20, count(And(Where("status", Eq("active")), Where("region", Eq("eu")))))
+	assert.Equal(t, 80, count(Or(Where("status", Eq("active")), Where("region", Eq("eu")))))
+	assert.Equal(t, 80, count(Not(Where("status", Eq("active")))))
+	assert.Equal(t, 60, count(Where("plan", In("free", "team"))))
+	assert.Equal(t, 60, count(Where("plan", Between("f", "q"))))
+
+	// mixed with tags
+	assert.Equal(t, 10, count(And(Where("tier", Eq("top")), Where("status", Eq("trial")))))
+
+	plan, err := col.Select(And(Where("status", Eq("active")), Not(Where("region", Eq("us"))))).Explain()
+	assert.NoError(t, err)
+	assert.Equal(t, `bitmap and (20 keys)
+  bitmap status = "active" (40 keys)
+  bitmap not (60 keys)
+    bitmap region = "us" (60 keys)
+`, plan)
+
+	items, err := col.Select(Where("status", Eq("suspended"))).OrderBy("plan", true).Limit(3).All()
+	assert.NoError(t, err)
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "team", items[0].Plan)
+		assert.Equal(t, []string{"010", "022"}, []string{items[0].ID, items[1].ID})
+	}
+
+	// the bitmaps are rebuilt with the next generation
+	accounts = accounts[:60]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 20, col.Count("status", "active"))
+	assert.Equal(t, 10, count(And(Where("status", Eq("active")), Where("region", Eq("eu")))))
+}
@@ -0,0 +1,191 @@
+package inventory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Invalidation is a message about items that were changed in the origin
+// source of a kind
+type Invalidation struct {
+	// Origin identifies the Invalidator that published the message
+	Origin string `json:"origin"`
+
+	Kind string `json:"kind"`
+
+	// Keys are the primary key values of the changed items. no keys means
+	// that all the items of the kind might have changed
+	Keys []string `json:"keys,omitempty"`
+}
+
+// InvalidationBus broadcasts invalidations between the instances of a
+// service, so all of them reload the data that was changed through one of
+// them
+type InvalidationBus interface {
+	// Publish sends the provided invalidation to the other instances
+	Publish(ctx context.Context, inv Invalidation) error
+
+	// Subscribe calls fn with every received invalidation until ctx is done.
+	// invalidations that were published by the same instance might be
+	// received as well
+	Subscribe(ctx context.Context, fn func(Invalidation)) error
+}
+
+// NewInvalidator creates an Invalidator that broadcasts invalidations over the
+// provided bus
+func NewInvalidator(bus InvalidationBus, opts ...InvalidatorOpt) *Invalidator {
+	i := &Invalidator{
+		bus:         bus,
+		origin:      newOrigin(),
+		logger:      slog.Default(),
+		collections: map[string]Reloadable{},
+	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// Invalidator reloads the registered collections locally and broadcasts the
+// invalidation to the other instances, which reload them as well once they
+// receive it. invalidations that it published itself are ignored when they
+// are received back
+type Invalidator struct {
+	bus    InvalidationBus
+	origin string
+	logger *slog.Logger
+
+	mu          sync.RWMutex
+	collections map[string]Reloadable
+}
+
+// InvalidatorOpt is an option for instrumenting an Invalidator
+type InvalidatorOpt func(*Invalidator)
+
+// InvalidatorOrigin sets the id of the instance in the published
+// invalidations. by default, a random id that is prefixed with the hostname is
+// generated
+func InvalidatorOrigin(origin string) InvalidatorOpt {
+	return func(i *Invalidator) {
+		i.origin = origin
+	}
+}
+
+// InvalidatorLogger sets the logger the Invalidator reports failed reloads
+// to. by default, slog.Default() is used
+func InvalidatorLogger(logger *slog.Logger) InvalidatorOpt {
+	return func(i *Invalidator) {
+		i.logger = logger
+	}
+}
+
+// InvalidatorCollections registers the provided collections on the
+// Invalidator
+func InvalidatorCollections(collections ...Reloadable) InvalidatorOpt {
+	return func(i *Invalidator) {
+		i.Register(collections...)
+	}
+}
+
+func newOrigin() string {
+	host, _ := os.Hostname()
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return fmt.Sprintf("%s-%s", host, hex.EncodeToString(b))
+}
+
+// Origin returns the id of the instance in the published invalidations
+func (i *Invalidator) Origin() string {
+	return i.origin
+}
+
+// Register registers the provided collections on the Invalidator, so they
+// are reloaded by invalidations of their kinds
+func (i *Invalidator) Register(collections ...Reloadable) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, col := range collections {
+		i.collections[col.Kind()] = col
+	}
+}
+
+// Invalidate reloads the items of the provided primary key values, or all the
+// items if no keys are provided, of the provided kind and then publishes the
+// invalidation to the other instances. the invalidation is published even if
+// the local reload fails, since it doesn't tell whether the other instances
+// would fail too
+func (i *Invalidator) Invalidate(ctx context.Context, kind string, keys ...string) error {
+	reloadErr := i.reload(ctx, kind, keys)
+
+	publishErr := i.bus.Publish(ctx, Invalidation{Origin: i.origin, Kind: kind, Keys: keys})
+	if publishErr != nil {
+		publishErr = fmt.Errorf("failed to publish invalidation of %q: %w", kind, publishErr)
+	}
+
+	return errors.Join(reloadErr, publishErr)
+}
+
+// Run reloads the collections by the invalidations that are received from
+// other instances until ctx is done
+func (i *Invalidator) Run(ctx context.Context) error {
+	return i.bus.Subscribe(ctx, func(inv Invalidation) {
+		if inv.Origin == i.origin {
+			return
+		}
+
+		if err := i.reload(ctx, inv.Kind, inv.Keys); err != nil {
+			i.logger.Error("failed to reload on invalidation", "kind", inv.Kind, "origin", inv.Origin, "error", err)
+		}
+	})
+}
+
+func (i *Invalidator) reload(ctx context.Context, kind string, keys []string) (err error) {
+	i.mu.RLock()
+	col, ok := i.collections[kind]
+	i.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("kind %q is not registered", kind)
+	}
+
+	if len(keys) == 0 {
+		_, err = col.Reload(ctx)
+	} else {
+		_, err = col.ReloadKeys(ctx, keys...)
+	}
+
+	return
+}
+
+// NewMemoryBus creates a MemoryBus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// MemoryBus is an InvalidationBus in memory that delivers every invalidation
+// to all of its subscribers, including the publisher. it simulates instances
+// of a service within a single process
+type MemoryBus struct {
+	fanout fanout[Invalidation]
+}
+
+// Publish delivers the provided invalidation to all the subscribers
+func (b *MemoryBus) Publish(ctx context.Context, inv Invalidation) error {
+	return b.fanout.publish(ctx, inv)
+}
+
+// Subscribe calls fn with every published invalidation until ctx is done
+func (b *MemoryBus) Subscribe(ctx context.Context, fn func(Invalidation)) error {
+	return b.fanout.subscribe(ctx, nil, fn)
+}
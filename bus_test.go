@@ -0,0 +1,169 @@
+package inventory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// replica is an instance of a service that serves the books of a shared
+// source
+type replica struct {
+	col     *Collection[*book]
+	inv     *Invalidator
+	reloads atomic.Int32
+}
+
+func newReplica(t *testing.T, bus InvalidationBus, source *sync.Map) *replica {
+	r := &replica{}
+
+	r.col = NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			r.reloads.Add(1)
+			source.Range(func(_, v any) bool {
+				load(v.(*book))
+				return true
+			})
+
+			return nil
+		}),
+		KeyedSource(func(ctx context.Context, keys []string, load func(in ...*book)) error {
+			for _, key := range keys {
+				if v, ok := source.Load(key); ok {
+					load(v.(*book))
+				}
+			}
+
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+	)
+
+	r.inv = NewInvalidator(bus,
+		InvalidatorCollections(r.col),
+		InvalidatorLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.inv.Run(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	_, err := r.col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	return r
+}
+
+func (r *replica) title(id string) string {
+	b, ok := r.col.GetBy("id")(id)
+	if !ok {
+		return ""
+	}
+
+	return b.Title
+}
+
+func testInvalidationBus(t *testing.T, a, b InvalidationBus) {
+	source := &sync.Map{}
+	source.Store("1", &book{"1", "Dune", "Frank Herbert"})
+
+	ra := newReplica(t, a, source)
+	rb := newReplica(t, b, source)
+
+	source.Store("1", &book{"1", "Dune (1965)", "Frank Herbert"})
+	assert.NoError(t, ra.inv.Invalidate(context.Background(), "books", "1"))
+
+	assert.Equal(t, "Dune (1965)", ra.title("1"))
+	assert.Eventually(t, func() bool { return rb.title("1") == "Dune (1965)" }, time.Second, time.Millisecond)
+
+	source.Store("2", &book{"2", "Dune Messiah", "Frank Herbert"})
+	assert.NoError(t, rb.inv.Invalidate(context.Background(), "books"))
+
+	assert.Eventually(t, func() bool { return ra.title("2") == "Dune Messiah" }, time.Second, time.Millisecond)
+
+	// the echo of an invalidation isn't reloaded by its origin
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), ra.reloads.Load())
+	assert.Equal(t, int32(2), rb.reloads.Load())
+
+	err := ra.inv.Invalidate(context.Background(), "authors")
+	assert.EqualError(t, err, `kind "authors" is not registered`)
+}
+
+func TestMemoryBus(t *testing.T) {
+	bus := NewMemoryBus()
+
+	subscribed := func(n int) func() bool {
+		return func() bool {
+			return bus.fanout.accepted(Invalidation{}) == n
+		}
+	}
+
+	t.Run("invalidator", func(t *testing.T) {
+		source := &sync.Map{}
+		r := newReplica(t, bus, source)
+		assert.Eventually(t, subscribed(1), time.Second, time.Millisecond)
+
+		other := NewInvalidator(bus, InvalidatorOrigin(r.inv.Origin()))
+		assert.NoError(t, bus.Publish(context.Background(), Invalidation{Origin: other.Origin(), Kind: "books"}))
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(1), r.reloads.Load())
+
+		// an invalidation that fails to reload locally is published anyway
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan Invalidation, 1)
+		go func() {
+			_ = bus.Subscribe(ctx, func(inv Invalidation) {
+				if inv.Kind == "authors" {
+					received <- inv
+				}
+			})
+		}()
+		assert.Eventually(t, subscribed(2), time.Second, time.Millisecond)
+
+		err := other.Invalidate(context.Background(), "authors", "1")
+		assert.EqualError(t, err, `kind "authors" is not registered`)
+
+		select {
+		case inv := <-received:
+			assert.Equal(t, []string{"1"}, inv.Keys)
+		case <-time.After(time.Second):
+			t.Fatal("the invalidation wasn't published")
+		}
+	})
+
+	assert.Eventually(t, subscribed(0), time.Second, time.Millisecond)
+
+	t.Run("replicas", func(t *testing.T) {
+		testInvalidationBus(t, &waitingBus{bus, subscribed}, bus)
+	})
+}
+
+// waitingBus publishes only once both replicas are subscribed
+type waitingBus struct {
+	*MemoryBus
+
+	subscribed func(n int) func() bool
+}
+
+func (b *waitingBus) Publish(ctx context.Context, inv Invalidation) error {
+	for !b.subscribed(2)() {
+		time.Sleep(time.Millisecond)
+	}
+
+	return b.MemoryBus.Publish(ctx, inv)
+}
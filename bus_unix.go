@@ -0,0 +1,115 @@
+//go:build unix
+
+package inventory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// NewUnixBus creates a UnixBus that binds a socket in the provided directory
+func NewUnixBus(dir string) (b *UnixBus, err error) {
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s.sock", os.Getpid(), hex.EncodeToString(id)))
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+
+	return &UnixBus{dir: dir, path: path, conn: conn}, nil
+}
+
+// UnixBus is an InvalidationBus between the instances of a service on the
+// same host. every instance binds a datagram socket in a shared directory and
+// publishes to the sockets of all the other instances in it. sockets of
+// instances that are gone are removed once publishing to them fails
+type UnixBus struct {
+	dir  string
+	path string
+	conn *net.UnixConn
+}
+
+// Publish sends the provided invalidation to all the other sockets in the
+// directory of the bus
+func (b *UnixBus) Publish(ctx context.Context, inv Invalidation) (err error) {
+	msg, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+
+	peers, err := filepath.Glob(filepath.Join(b.dir, "*.sock"))
+	if err != nil {
+		return
+	}
+
+	var errs []error
+	for _, peer := range peers {
+		if peer == b.path {
+			continue
+		}
+
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		_, err = b.conn.WriteToUnix(msg, &net.UnixAddr{Name: peer, Net: "unixgram"})
+		switch {
+		case err == nil:
+		case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ENOENT):
+			_ = os.Remove(peer)
+		default:
+			errs = append(errs, fmt.Errorf("failed to publish to %s: %w", peer, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Subscribe calls fn with every invalidation that is received on the socket
+// of the bus until ctx is done
+func (b *UnixBus) Subscribe(ctx context.Context, fn func(Invalidation)) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = b.conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := b.conn.ReadFromUnix(buf)
+		if ctx.Err() != nil {
+			_ = b.conn.SetReadDeadline(time.Time{})
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var inv Invalidation
+		if err = json.Unmarshal(buf[:n], &inv); err != nil {
+			continue
+		}
+
+		fn(inv)
+	}
+}
+
+// Close closes the socket of the bus and removes it from the directory
+func (b *UnixBus) Close() error {
+	err := b.conn.Close()
+	_ = os.Remove(b.path)
+
+	return err
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package inventory
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixBus(t *testing.T) {
+	dir, err := os.MkdirTemp("", "bus")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := NewUnixBus(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { _ = a.Close() })
+
+	b, err := NewUnixBus(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { _ = b.Close() })
+
+	// a socket of an instance that is gone
+	gone, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "gone.sock"), Net: "unixgram"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_ = gone.Close()
+
+	testInvalidationBus(t, a, b)
+
+	_, err = os.Stat(filepath.Join(dir, "gone.sock"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NoError(t, a.Publish(context.Background(), Invalidation{Kind: "books"}))
+}
@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// cidrIndex is an index of the collection by IP prefixes. the items are tagged
+// by their prefixes like any other index, and the radix tree of the prefixes
+// is built from the tags of the index once per version of the collection
+type cidrIndex[T any] struct {
+	name string
+	ref  indexFn[T]
+
+	mu      sync.Mutex
+	version uint64
+	v4, v6  *cidrNode
+}
+
+// cidrNode is a node of a binary radix tree, in which every level is a bit of
+// the address
+type cidrNode struct {
+	children [2]*cidrNode
+	prefixes []string
+}
+
+// CIDRIndex adds an index of the collection by IPv4 and IPv6 prefixes, like
+// "10.0.0.0/8", or addresses, which are single address prefixes. it is
+// queried by LookupIP and LookupIPAll
+func CIDRIndex[T any](name string, value indexFn[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.cidrs = append(c.cidrs, &cidrIndex[T]{name: name, ref: value})
+	}
+}
+
+// LookupIP returns the items of the most specific prefix of the provided
+// CIDR index that contains the provided address
+func (c *Collection[T]) LookupIP(index string, addr netip.Addr) ([]T, error) {
+	return c.matchCIDR(index, addr, true)
+}
+
+// LookupIPAll returns the items of all the prefixes of the provided CIDR index
+// that contain the provided address, from the most specific
+func (c *Collection[T]) LookupIPAll(index string, addr netip.Addr) ([]T, error) {
+	return c.matchCIDR(index, addr, false)
+}
+
+func (c *Collection[T]) matchCIDR(index string, addr netip.Addr, specific bool) (res []T, err error) {
+	i := slices.IndexFunc(c.cidrs, func(idx *cidrIndex[T]) bool { return idx.name == index })
+	if i < 0 {
+		return nil, fmt.Errorf("collection %q has no CIDR index %q", c.kind, index)
+	}
+
+	idx := c.cidrs[i]
+	addr = addr.Unmap()
+
+	err = c.db.View(func(viewer DBViewer) error {
+		n := idx.build(viewer, c.kind, addr.Is4())
+
+		// the prefixes of the path to the address, from the least specific
+		var matches []string
+		for bit := 0; n != nil; bit++ {
+			matches = append(matches, n.prefixes...)
+			if bit == addr.BitLen() {
+				break
+			}
+
+			n = n.children[addrBit(addr, bit)]
+		}
+
+		slices.Reverse(matches)
+		if specific && len(matches) > 0 {
+			matches = matches[:1]
+		}
+
+		for _, p := range matches {
+			viewer.Iter(mkKey(c.kind, idx.name, p), func(key string, getVal func() (any, bool)) bool {
+				i, ok := getVal()
+				if !ok {
+					return true
+				}
+
+				t, ok := i.(T)
+				if !ok {
+					err = fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+					return false
+				}
+
+				res = append(res, t)
+
+				return true
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// build returns the radix tree of the prefixes of the index in the provided
+// viewer of the provided family, which is rebuilt from the tags of the index
+// if the collection has changed since it was last built
+func (idx *cidrIndex[T]) build(viewer DBViewer, kind string, v4 bool) *cidrNode {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	version := viewer.TagVersion(itemsTag(kind))
+	if idx.v4 == nil || idx.version != version {
+		idx.v4, idx.v6 = &cidrNode{}, &cidrNode{}
+		idx.version = version
+
+		indexValues(viewer, kind, idx.name, func(v, _ string, _ int) bool {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return true
+			}
+
+			n := idx.v6
+			if p.Addr().Is4() {
+				n = idx.v4
+			}
+
+			for bit := 0; bit < p.Bits(); bit++ {
+				b := addrBit(p.Addr(), bit)
+				if n.children[b] == nil {
+					n.children[b] = &cidrNode{}
+				}
+
+				n = n.children[b]
+			}
+
+			n.prefixes = append(n.prefixes, v)
+
+			return true
+		})
+	}
+
+	if v4 {
+		return idx.v4
+	}
+
+	return idx.v6
+}
+
+// addrBit returns the bit of the provided address at the provided position,
+// from the most significant
+func addrBit(addr netip.Addr, bit int) int {
+	b := addr.AsSlice()
+	return int(b[bit/8]>>(7-bit%8)) & 1
+}
+
+// parseCIDR parses a prefix or an address to its canonical masked prefix
+func parseCIDR(v string) (netip.Prefix, error) {
+	if !strings.Contains(v, "/") {
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		addr = addr.Unmap()
+
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	p, err := netip.ParsePrefix(v)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if p.Addr().Is4In6() {
+		if p.Bits() < 96 {
+			return netip.Prefix{}, fmt.Errorf("prefix %q of an IPv4-mapped address is shorter than /96", v)
+		}
+
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+
+	return p.Masked(), nil
+}
+
+// tagItemWithCIDRs tags the item of the provided key with its prefixes of the
+// CIDR indexes of the collection. invalid prefixes are logged and ignored
+func (c *Collection[T]) tagItemWithCIDRs(writer DBWriter, key string, item T) {
+	for _, idx := range c.cidrs {
+		idx.ref(item, func(v string) {
+			p, err := parseCIDR(v)
+			if err != nil {
+				c.log().Warn("invalid prefix", "kind", c.kind, "index", idx.name, "key", key, "error", err)
+				return
+			}
+
+			tagIndexValue(writer, key, c.kind, idx.name, p.String())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package inventory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type policy struct {
+	Name     string
+	Networks []string
+}
+
+func TestCIDRIndex(t *testing.T) {
+	policies := []*policy{
+		{"default", []string{"0.0.0.0/0", "::/0"}},
+		{"office", []string{"10.1.0.0/16", "2001:db8::/32"}},
+		{"lab", []string{"10.1.2.0/24"}},
+		{"admin", []string{"10.1.2.3", "::ffff:10.1.2.4"}},
+		{"broken", []string{"10.1.2.300/32"}},
+	}
+
+	col := NewCollection[*policy](NewDB(), "policies",
+		Source(func(ctx context.Context, load func(in ...*policy)) error {
+			load(policies...)
+			return nil
+		}),
+		PrimaryKey("name", func(p *policy, val func(string)) { val(p.Name) }),
+		CIDRIndex("network", func(p *policy, val func(string)) {
+			for _, n := range p.Networks {
+				val(n)
+			}
+		}),
+		Logger[*policy](slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	names := func(res []*policy, err error) (names []string) {
+		assert.NoError(t, err)
+		for _, p := range res {
+			names = append(names, p.Name)
+		}
+
+		return
+	}
+
+	ip := netip.MustParseAddr
+
+	assert.Equal(t, []string{"admin"}, names(col.LookupIP("network", ip("10.1.2.3"))))
+	assert.Equal(t, []string{"admin"}, names(col.LookupIP("network", ip("::ffff:10.1.2.4"))))
+	assert.Equal(t, []string{"lab"}, names(col.LookupIP("network", ip("10.1.2.5"))))
+	assert.Equal(t, []string{"office"}, names(col.LookupIP("network", ip("10.1.3.1"))))
+	assert.Equal(t, []string{"default"}, names(col.LookupIP("network", ip("192.168.1.1"))))
+	assert.Equal(t, []string{"office"}, names(col.LookupIP("network", ip("2001:db8::1"))))
+	assert.Equal(t, []string{"default"}, names(col.LookupIP("network", ip("2001:db9::1"))))
+
+	assert.Equal(t, []string{"admin", "lab", "office", "default"}, names(col.LookupIPAll("network", ip("10.1.2.3"))))
+
+	_, err = col.LookupIP("subnet", ip("10.1.2.3"))
+	assert.EqualError(t, err, `collection "policies" has no CIDR index "subnet"`)
+
+	// the tree is rebuilt with the next generation
+	policies = policies[1:]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	assert.Empty(t, names(col.LookupIP("network", ip("192.168.1.1"))))
+	assert.Equal(t, []string{"lab", "office"}, names(col.LookupIPAll("network", ip("10.1.2.5"))))
+}
+
+func Test_parseCIDR(t *testing.T) {
+	p, err := parseCIDR("::ffff:10.1.2.3/104")
+	assert.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), p)
+
+	p, err = parseCIDR("::ffff:10.1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("10.1.2.3/32"), p)
+
+	_, err = parseCIDR("::ffff:10.1.2.3/80")
+	assert.EqualError(t, err, `prefix "::ffff:10.1.2.3/80" of an IPv4-mapped address is shorter than /96`)
+}
@@ -1,8 +1,13 @@
 package inventory
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"slices"
+	"sync"
+	"time"
 )
 
 // NewCollection creates a Collection of T with the provided opts; PrimaryKey
@@ -20,12 +25,124 @@ func NewCollection[T any](db DB, kind string, opts ...CollectionOpt[T]) (c *Coll
 // because the purpose of this repository is IoC of the data, it also defines
 // how the data is loaded from the cold source.
 type Collection[T any] struct {
-	db         DB
-	kind       string
-	pk         index[T]
-	keys       []index[T]
-	extract    extractFn[T]
-	inferences []inferFn[T]
+	db          DB
+	kind        string
+	pk          index[T]
+	keys        []index[T]
+	texts       []textIndex[T]
+	tries       []*trieIndex[T]
+	cidrs       []*cidrIndex[T]
+	geos        []*geoIndex[T]
+	bitmaps     bitmaps[T]
+	sources     sources[T]
+	reloaded    []func()
+	fullyLoaded []func()
+	extract     ExtractFunc[T]
+	extractKey  KeyedExtractFunc[T]
+	inferences  []inferFn[T]
+	policy      ConstraintPolicy
+	validators  []func(T) error
+	batchVals   []BatchValidator[T]
+	keepGens    int
+	logger      *slog.Logger
+	retry       RetryPolicy
+	breaker     *breaker
+	loader      LoadFunc[T]
+	negativeTTL time.Duration
+
+	reloading   sync.Mutex
+	mu          sync.Mutex
+	stats       Stats
+	generations []*generation[T]
+	lastGen     int
+	lazy        lazyState
+}
+
+// Stats describes the state of a Collection as of its last reload
+type Stats struct {
+	Kind         string        `json:"kind"`
+	Items        int           `json:"items"`
+	Generation   int           `json:"generation"`
+	Version      uint64        `json:"version"`
+	Reloads      int           `json:"reloads"`
+	Failures     int           `json:"failures"`
+	LastReload   time.Time     `json:"lastReload"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastError    string        `json:"lastError,omitempty"`
+	Violations   []Violation   `json:"violations,omitempty"`
+
+	// Loads is the number of items that were loaded on demand by ReadThrough
+	Loads int `json:"loads,omitempty"`
+
+	// Retries is the number of failed extractions that were retried
+	Retries int `json:"retries,omitempty"`
+
+	// Breaker is the state of the circuit breaker, if the collection has one
+	Breaker BreakerState `json:"breaker,omitempty"`
+
+	// Offset is the sequence number of the last change that was applied
+	// from a ChangeStream
+	Offset uint64 `json:"offset,omitempty"`
+}
+
+// ReloadResult describes the outcome of a reload of a Collection
+type ReloadResult struct {
+	Items      int           `json:"items"`
+	Version    uint64        `json:"version"`
+	Violations []Violation   `json:"violations,omitempty"`
+	Duration   time.Duration `json:"duration"`
+
+	// Unchanged reports that the origin source had no changes since the last
+	// reload, so the reload was skipped
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// ErrNotModified is returned by an ExtractFunc in order to report that the
+// origin source has not changed since the last reload, so the reload is
+// skipped and the current items continue to be served
+var ErrNotModified = errors.New("not modified")
+
+// Reloadable is the untyped handle of a Collection, for tools that operate on
+// collections regardless of the type of their items
+type Reloadable interface {
+	// Kind returns the kind of the items in the collection
+	Kind() string
+
+	// Stats returns the stats of the collection as of its last reload
+	Stats() Stats
+
+	// Invalidate reloads all data from the origin source
+	Invalidate()
+
+	// Reload reloads all data from the origin source and reports the result
+	Reload(ctx context.Context) (ReloadResult, error)
+
+	// ReloadKeys reloads the items of the provided primary key values from
+	// the origin source and reports the result
+	ReloadKeys(ctx context.Context, vals ...string) (ReloadResult, error)
+
+	count() int
+	lookup(index, val string) (any, bool)
+}
+
+// Kind returns the kind of the items in the collection
+func (c *Collection[T]) Kind() string {
+	return c.kind
+}
+
+// Stats returns the stats of the collection as of its last reload
+func (c *Collection[T]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Kind = c.kind
+	stats.Version = c.Version()
+	if c.breaker != nil {
+		stats.Breaker = c.breaker.current()
+	}
+
+	return stats
 }
 
 // With instruments the collection with the provided opts
@@ -44,7 +161,7 @@ func Infer[Base, Inferred any](baseCol *Collection[Base], mapBy string, mapFn ma
 	baseCol.inferences = append(baseCol.inferences, func(writer DBWriter, base Base) {
 		mapFn(base, func(kv string, items ...Inferred) {
 			inferredCol.indexer(items, func(key string, item Inferred) {
-				inferredCol.loadItem(writer, key, item)
+				inferredCol.loadItem(writer, key, item, nil)
 				baseCol.pk.ref(base, func(v string) {
 					tag := mkKey(baseCol.kind, mapBy, kv)
 					writer.Tag(key, tag)
@@ -111,9 +228,15 @@ func Derive[In, Out any](collection *Collection[In], name string, fn func(in In)
 			return
 		}
 
-		var key string
+		// derivatives are tagged by their own kind, so filling them doesn't
+		// change the version of the collection, and are invalidated with the
+		// key of their item
+		kind := fmt.Sprintf("%s/%s", collection.kind, name)
+
+		var key, itemKey string
 		collection.pk.ref(in, func(v string) {
-			key = mkKey(fmt.Sprintf("%s/%s", collection.kind, name), collection.pk.key, v)
+			key = mkKey(kind, collection.pk.key, v)
+			itemKey = mkKey(collection.kind, collection.pk.key, v)
 		})
 
 		val, err := collection.db.GetOrFill(key, func() (res any, err error) {
@@ -123,7 +246,7 @@ func Derive[In, Out any](collection *Collection[In], name string, fn func(in In)
 			}
 
 			return
-		}, collection.kind)
+		}, kind, itemKey)
 
 		if err != nil {
 			return
@@ -141,31 +264,67 @@ func Derive[In, Out any](collection *Collection[In], name string, fn func(in In)
 // Extractor sets the extractFn of the collection. extractFn is a function
 // that extracts the data from the origin source
 func Extractor[T any](x extractFn[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.extract = func(_ context.Context, load func(in ...T)) error {
+			x(load)
+
+			return nil
+		}
+	}
+}
+
+// Source sets the ExtractFunc of the collection. unlike Extractor, the
+// ExtractFunc can fail the reload by returning an error
+func Source[T any](x ExtractFunc[T]) CollectionOpt[T] {
 	return func(c *Collection[T]) {
 		c.extract = x
 	}
 }
 
+// KeyedSource sets the KeyedExtractFunc of the collection, that is used for
+// reloading specific items by their primary key values
+func KeyedSource[T any](x KeyedExtractFunc[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.extractKey = x
+	}
+}
+
 // PrimaryKey sets the primary index of the collection
 func PrimaryKey[T any](name string, value indexFn[T]) CollectionOpt[T] {
 	return func(c *Collection[T]) {
-		c.addIndex(c.kind, name, true, value)
+		c.addIndex(c.kind, name, true, true, value)
 	}
 }
 
 // AdditionalKey adds a secondary index of the collection
 func AdditionalKey[T any](name string, value indexFn[T]) CollectionOpt[T] {
 	return func(c *Collection[T]) {
-		c.addIndex(c.kind, name, false, value)
+		c.addIndex(c.kind, name, false, false, value)
 	}
 }
 
-func (c *Collection[T]) addIndex(kind, key string, primary bool, value indexFn[T]) bool {
+// UniqueKey adds a secondary index of the collection whose values must be
+// unique. items that share a value are reported as violations and handled by
+// the ConstraintPolicy of the collection
+func UniqueKey[T any](name string, value indexFn[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.addIndex(c.kind, name, false, true, value)
+	}
+}
+
+// Logger sets the logger of the collection. by default, slog.Default() is used
+func Logger[T any](logger *slog.Logger) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.logger = logger
+	}
+}
+
+func (c *Collection[T]) addIndex(kind, key string, primary, unique bool, value indexFn[T]) bool {
 	if slices.ContainsFunc(c.keys, func(i index[T]) bool { return i.key == key }) {
 		return false
 	}
 
-	idx := index[T]{primary, kind, key, value}
+	idx := index[T]{primary, unique, kind, key, value}
 	if primary {
 		c.pk = idx
 	} else {
@@ -175,40 +334,83 @@ func (c *Collection[T]) addIndex(kind, key string, primary bool, value indexFn[T
 	return true
 }
 
-func (c *Collection[T]) index(key string, primary bool, value indexFn[T]) Getter[T] {
-	c.addIndex(c.kind, key, primary, value)
+func (c *Collection[T]) index(key string, primary, unique bool, value indexFn[T]) Getter[T] {
+	c.addIndex(c.kind, key, primary, unique, value)
 
 	return c.getter(key, primary)
 }
 
 func (c *Collection[T]) getter(key string, primary bool) Getter[T] {
-	return func(val string) (t T, ok bool) {
-		var i any
-		if !primary {
-			c.db.Iter(mkKey(c.kind, key, val), func(key string, getVal func() (any, bool)) (proceed bool) {
-				i, ok = getVal()
-				return false
-			})
-		} else {
-			i, ok = c.db.Get(mkKey(c.kind, key, val))
+	return func(val string) (T, bool) {
+		t, presence := c.find(c.db, key, primary, val)
+		if presence == NotLoaded {
+			c.dropExpired(key, val)
 		}
 
-		if !ok {
-			return
+		if presence == NotLoaded && c.loader != nil {
+			t, presence = c.readThrough(context.Background(), key, primary, val)
 		}
 
-		t, ok = i.(T)
+		return t, presence == Present
+	}
+}
+
+func (c *Collection[T]) get(viewer DBViewer, key string, primary bool, val string) (t T, ok bool) {
+	var i any
+	if !primary {
+		viewer.Iter(mkKey(c.kind, key, val), func(key string, getVal func() (any, bool)) (proceed bool) {
+			i, ok = getVal()
+			return false
+		})
+	} else {
+		i, ok = viewer.Get(mkKey(c.kind, key, val))
+	}
+
+	if !ok {
+		return
+	}
+
+	t, ok = i.(T)
+
+	return
+}
+
+// VersionedGetBy creates a VersionedGetter from existing index. the version is
+// the version of the collection the item was read from
+func (c *Collection[T]) VersionedGetBy(key string) VersionedGetter[T] {
+	primary := key == c.pk.key
+
+	return func(val string) (t T, version uint64, ok bool) {
+		_ = c.db.View(func(viewer DBViewer) error {
+			version = viewer.TagVersion(itemsTag(c.kind))
+			t, ok = c.get(viewer, key, primary, val)
+
+			return nil
+		})
 
 		return
 	}
 }
 
+// Version returns the version of the collection. the version changes
+// whenever the items of the collection are changed, but not by the negative
+// entries of its misses
+func (c *Collection[T]) Version() uint64 {
+	return c.db.TagVersion(itemsTag(c.kind))
+}
+
+// Changed reports whether the collection has changed since the provided
+// version
+func (c *Collection[T]) Changed(since uint64) bool {
+	return c.Version() != since
+}
+
 // PrimaryKey creates a primary index on the collection using an indexFn
 //
 // for example;
 // c.PrimaryKey("id", func(f Foo, val func(string)) { val(f.id) })
 func (c *Collection[T]) PrimaryKey(key string, value indexFn[T]) Getter[T] {
-	return c.index(key, true, value)
+	return c.index(key, true, true, value)
 }
 
 // AdditionalKey creates an additional index on the collection using an indexFn
@@ -216,7 +418,13 @@ func (c *Collection[T]) PrimaryKey(key string, value indexFn[T]) Getter[T] {
 // for example;
 // c.AdditionalKey("name", func(f Foo, val func(string)) { val(f.name) })
 func (c *Collection[T]) AdditionalKey(key string, value indexFn[T]) Getter[T] {
-	return c.index(key, false, value)
+	return c.index(key, false, false, value)
+}
+
+// UniqueKey creates an additional index on the collection whose values must be
+// unique, as enforced by the ConstraintPolicy of the collection
+func (c *Collection[T]) UniqueKey(key string, value indexFn[T]) Getter[T] {
+	return c.index(key, false, true, value)
 }
 
 // GetBy creates a getter from existing index
@@ -243,7 +451,7 @@ func (c *Collection[T]) Scalar(key, value string) Scalar[T] {
 // MapBy creates a Query from the provided key mapped by the provided indexFn,
 // to be used for querying the collection by a non-unique attribute
 func (c *Collection[T]) MapBy(key string, ref indexFn[T]) Query[T] {
-	c.addIndex(c.kind, key, false, ref)
+	c.addIndex(c.kind, key, false, false, ref)
 	return func(val string, filters ...func(T) bool) (res []T, err error) {
 		c.db.Iter(mkKey(c.kind, key, val), func(key string, getVal func() (any, bool)) (proceed bool) {
 			item, ok := getVal()
@@ -310,28 +518,329 @@ func (c *Collection[T]) Scan(consume func(T) bool, filters ...func(T) bool) {
 
 // Invalidate reloads all data from the origin source, defined by the Extractor
 func (c *Collection[T]) Invalidate() {
-	_ = c.db.Update(func(writer DBWriter) error {
-		writer.Invalidate(c.kind)
-		c.Load(writer)
+	_, _ = c.Reload(context.Background())
+}
 
-		return nil
+// Reload reloads all data from the origin source, defined by the Extractor,
+// and reports the result. if the reload fails, the previous generation of
+// the collection continues to be served
+func (c *Collection[T]) Reload(ctx context.Context) (res ReloadResult, err error) {
+	// reloads are serialized so the state that an ExtractFunc keeps after a
+	// commit is stored before the next reload extracts
+	c.reloading.Lock()
+	defer c.reloading.Unlock()
+
+	start := time.Now()
+
+	var (
+		gen   *generation[T]
+		hooks *commitHooks
+	)
+
+	err = c.attempt(ctx, func(ctx context.Context) error {
+		ctx, hooks = withCommitHooks(ctx)
+
+		// the items are extracted before the update, so the writes to the db
+		// aren't blocked by slow or retried extractions
+		staged, sourced, err := c.stage(ctx)
+		if err != nil {
+			return err
+		}
+
+		return c.db.Update(func(writer DBWriter) (err error) {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+
+			c.invalidate(writer)
+			res, gen, err = c.loadStaged(writer, staged, sourced)
+			if err == nil {
+				res.Version = writer.Version() + 1
+			}
+
+			return
+		})
 	})
+
+	res.Duration = time.Since(start)
+
+	if errors.Is(err, ErrNotModified) {
+		err = nil
+		res.Unchanged = true
+	} else if err == nil {
+		hooks.run()
+		c.committed(gen, start)
+		c.afterFullReload()
+	}
+
+	c.mu.Lock()
+	c.stats.Reloads++
+	c.stats.LastReload = start
+	c.stats.LastDuration = res.Duration
+	c.stats.Violations = res.Violations
+	if err != nil {
+		c.stats.Failures++
+		c.stats.LastError = err.Error()
+	} else {
+		c.stats.LastError = ""
+	}
+	c.mu.Unlock()
+
+	return
 }
 
-// Load loads all data from the origin source, defined by the Extractor
-func (c *Collection[T]) Load(writer DBWriter) {
-	c.extract(func(items ...T) {
+// Load loads all data from the origin source, defined by the Extractor, while
+// enforcing the constraints of the collection
+func (c *Collection[T]) Load(writer DBWriter) (res ReloadResult, err error) {
+	res, _, err = c.load(context.Background(), writer)
+
+	return
+}
+
+func (c *Collection[T]) load(ctx context.Context, writer DBWriter) (res ReloadResult, gen *generation[T], err error) {
+	staged, sourced, err := c.stage(ctx)
+	if err != nil {
+		return
+	}
+
+	return c.loadStaged(writer, staged, sourced)
+}
+
+// stage extracts the items of the collection along with the violations of
+// its named sources
+func (c *Collection[T]) stage(ctx context.Context) (staged []stagedItem[T], sourced []Violation, err error) {
+	if c.extract == nil && c.loader != nil {
+		// a read-through collection is loaded on demand
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicErr(r)
+		}
+	}()
+
+	load := func(items ...T) {
 		c.indexer(items, func(key string, item T) {
-			c.loadItem(writer, key, item)
+			staged = append(staged, stagedItem[T]{key, item})
 		})
+	}
+
+	if len(c.sources.list) > 0 {
+		sourced, err = c.mergeSources(ctx, load)
+	} else {
+		err = c.extract(ctx, load)
+	}
+
+	if err != nil && !errors.Is(err, ErrNotModified) {
+		err = &extractError{fmt.Errorf("failed to extract %q: %w", c.kind, err)}
+	}
+
+	return
+}
+
+// loadStaged loads the staged items while enforcing the constraints of the
+// collection
+func (c *Collection[T]) loadStaged(writer DBWriter, staged []stagedItem[T], sourced []Violation) (res ReloadResult, gen *generation[T], err error) {
+	if c.extract == nil && c.loader != nil {
+		gen = &generation[T]{}
+		return
+	}
+
+	items, owners, violations := c.resolve(staged)
+	violations = append(sourced, violations...)
+	res.Violations = violations
+
+	if err = c.enforce(violations); err != nil {
+		return
+	}
+
+	if err = c.validateItems(items); err != nil {
+		return
+	}
+
+	if err = c.validateBatch(items); err != nil {
+		return
+	}
+
+	for _, s := range items {
+		c.loadItem(writer, s.key, s.item, owners)
+	}
+
+	res.Items = len(items)
+	gen = &generation[T]{items: items, owners: owners}
+
+	return
+}
+
+// InvalidateKeys reloads the items of the provided primary key values from the
+// origin source, defined by the KeyedSource
+func (c *Collection[T]) InvalidateKeys(vals ...string) {
+	_, _ = c.ReloadKeys(context.Background(), vals...)
+}
+
+// ReloadKeys reloads the items of the provided primary key values from the
+// origin source, defined by the KeyedSource, and reports the result. items
+// that are no longer extracted are deleted along with their derivatives.
+// constraints are checked only among the reloaded items
+func (c *Collection[T]) ReloadKeys(ctx context.Context, vals ...string) (res ReloadResult, err error) {
+	extractKey := c.extractKey
+	if extractKey == nil && c.loader != nil {
+		extractKey = c.extractKeysByLoader
+	}
+
+	if extractKey == nil {
+		err = fmt.Errorf("collection %q has no keyed source", c.kind)
+		return
+	}
+
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	var staged []stagedItem[T]
+	err = c.attempt(ctx, func(ctx context.Context) (err error) {
+		staged = nil
+		err = extractKey(ctx, vals, func(items ...T) {
+			c.indexer(items, func(key string, item T) {
+				staged = append(staged, stagedItem[T]{key, item})
+			})
+		})
+		if err != nil {
+			err = &extractError{fmt.Errorf("failed to extract %d keys of %q: %w", len(vals), c.kind, err)}
+		}
+
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	keys := make([]string, 0, 2*len(vals))
+	for _, v := range vals {
+		keys = append(keys, mkKey(c.kind, c.pk.key, v), absentKey(c.kind, c.pk.key, v))
+	}
+
+	for _, s := range staged {
+		keys = append(keys, c.absentKeys(s.item)...)
+	}
+
+	var delta *generation[T]
+	if res, delta, err = c.replace(keys, staged); err == nil {
+		c.committed(c.patch(keys, delta), start)
+	}
+
+	return
+}
+
+// invalidate deletes all the items of the collection along with the items
+// that are tagged by their keys, such as their projections and derivatives
+func (c *Collection[T]) invalidate(writer DBWriter) {
+	for _, key := range writer.Invalidate(c.kind) {
+		writer.Invalidate(key)
+	}
+
+	for _, idx := range c.keys {
+		writer.Invalidate(valuesTag(c.kind, idx.key))
+	}
+	for _, idx := range c.tries {
+		writer.Invalidate(valuesTag(c.kind, idx.name))
+	}
+	for _, idx := range c.cidrs {
+		writer.Invalidate(valuesTag(c.kind, idx.name))
+	}
+}
+
+// afterReload calls the funcs that are registered to be called after every
+// committed reload of the collection
+func (c *Collection[T]) afterReload() {
+	for _, fn := range c.reloaded {
+		fn()
+	}
+}
+
+// afterFullReload calls the funcs that are registered to be called after
+// every committed reload or rollback of all the items of the collection, but
+// not after changes of some of them
+func (c *Collection[T]) afterFullReload() {
+	for _, fn := range c.fullyLoaded {
+		fn()
+	}
+}
+
+// replace deletes the items of the provided keys, along with their
+// derivatives, and loads the provided items instead. it returns the delta of
+// the items it loaded
+func (c *Collection[T]) replace(keys []string, staged []stagedItem[T]) (res ReloadResult, delta *generation[T], err error) {
+	items, owners, violations := c.resolve(staged)
+	res.Violations = violations
+
+	if err = c.enforce(violations); err != nil {
+		return
+	}
+
+	if err = c.validateItems(items); err != nil {
+		return
+	}
+
+	err = c.db.Update(func(writer DBWriter) error {
+		for _, key := range keys {
+			writer.Invalidate(key)
+			writer.Delete(key)
+		}
+
+		for _, s := range items {
+			c.loadItem(writer, s.key, s.item, owners)
+		}
+
+		res.Version = writer.Version() + 1
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	res.Items = len(items)
+	delta = &generation[T]{items: items, owners: owners}
+
+	return
+}
+
+func (c *Collection[T]) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+
+	return slog.Default()
+}
+
+// count returns the number of items currently in the collection
+func (c *Collection[T]) count() int {
+	return c.db.Count(itemsTag(c.kind))
+}
+
+// lookup fetches a loaded item by the provided index regardless of its type.
+// it doesn't read through, so it has no effect on the collection
+func (c *Collection[T]) lookup(index, val string) (item any, ok bool) {
+	_ = c.db.View(func(viewer DBViewer) error {
+		item, ok = c.get(viewer, index, index == c.pk.key, val)
+		return nil
 	})
+
+	return
 }
 
-func (c *Collection[T]) loadItem(writer DBWriter, key string, item T) {
+// loadItem puts the item in the db and tags it with the indexes of the
+// collection. owners are the keys that own tags of unique indexes that were
+// violated, so other keys will not be tagged with them
+func (c *Collection[T]) loadItem(writer DBWriter, key string, item T, owners map[string]string) {
 	writer.Put(key, item)
-	writer.Tag(key, c.kind)
+	writer.Tag(key, c.kind, itemsTag(c.kind))
 
-	c.tagItemWithIndexes(writer, key, item)
+	c.tagItemWithIndexes(writer, key, item, owners)
+	c.indexText(writer, key, item)
+	c.tagItemWithTries(writer, key, item)
+	c.tagItemWithCIDRs(writer, key, item)
 
 	for _, infer := range c.inferences {
 		infer(writer, item)
@@ -340,11 +849,14 @@ func (c *Collection[T]) loadItem(writer DBWriter, key string, item T) {
 	return
 }
 
-func (c *Collection[T]) tagItemWithIndexes(writer DBWriter, key string, item T) {
+func (c *Collection[T]) tagItemWithIndexes(writer DBWriter, key string, item T, owners map[string]string) {
 	for _, idx := range c.keys {
 		idx.ref(item, func(v string) {
-			tag := mkKey(c.kind, idx.key, v)
-			writer.Tag(key, tag)
+			if owner, ok := owners[mkKey(c.kind, idx.key, v)]; ok && owner != key {
+				return
+			}
+
+			tagIndexValue(writer, key, c.kind, idx.key, v)
 		})
 	}
 }
@@ -362,10 +874,11 @@ func (c *Collection[T]) indexer(items []T, fn func(key string, item T)) {
 }
 
 type index[T any] struct {
-	pk   bool
-	kind string
-	key  string
-	ref  indexFn[T]
+	pk     bool
+	unique bool
+	kind   string
+	key    string
+	ref    indexFn[T]
 }
 
 type CollectionOpt[T any] func(*Collection[T])
@@ -377,3 +890,11 @@ type mapFn[From, To any] func(From, func(kv string, items ...To))
 type inferFn[T any] func(DBWriter, T)
 
 type extractFn[T any] func(load func(in ...T))
+
+// ExtractFunc is a function that extracts all the items of a collection from
+// the origin source and may fail doing so
+type ExtractFunc[T any] func(ctx context.Context, load func(in ...T)) error
+
+// KeyedExtractFunc is a function that extracts the items of the provided
+// primary key values from the origin source
+type KeyedExtractFunc[T any] func(ctx context.Context, keys []string, load func(in ...T)) error
@@ -1,10 +1,12 @@
 package inventory
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -243,3 +245,48 @@ func Benchmark_collection(b *testing.B) {
 		}
 	})
 }
+
+func TestCollectionVersion(t *testing.T) {
+	db := NewDB()
+
+	bars := []*barItem{{meta: meta{"1", "bar1"}, barValue: "I'm bar"}}
+
+	barCol := NewCollection[*barItem](db, "bar",
+		Extractor(func(load func(in ...*barItem)) { load(bars...) }),
+		PrimaryKey("id", func(item *barItem, keyVal func(string)) { keyVal(item.id) }),
+	)
+	fooCol := NewCollection[*fooItem](db, "foo",
+		Extractor(func(load func(in ...*fooItem)) {}),
+		PrimaryKey("id", func(item *fooItem, keyVal func(string)) { keyVal(item.id) }),
+	)
+
+	getBar := barCol.VersionedGetBy("id")
+
+	_, version, ok := getBar("1")
+	assert.False(t, ok)
+	assert.Equal(t, uint64(0), version)
+
+	res, err := barCol.Reload(context.Background())
+	assert.NoError(t, err)
+
+	bar, version, ok := getBar("1")
+	assert.True(t, ok)
+	assert.Equal(t, "I'm bar", bar.barValue)
+	assert.Equal(t, res.Version, version)
+	assert.Equal(t, version, barCol.Version())
+	assert.False(t, barCol.Changed(version))
+
+	fooCol.Invalidate()
+	assert.False(t, barCol.Changed(version))
+
+	// filling a derivative doesn't change the items of the collection
+	der := Derive(barCol, "upper", func(bar *barItem) (string, error) { return strings.ToUpper(bar.barValue), nil })
+	upper, err := der(bar)
+	assert.NoError(t, err)
+	assert.Equal(t, "I'M BAR", upper)
+	assert.False(t, barCol.Changed(version))
+
+	barCol.Invalidate()
+	assert.True(t, barCol.Changed(version))
+	assert.Equal(t, barCol.Version(), barCol.Stats().Version)
+}
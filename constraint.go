@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConstraintPolicy defines how a Collection reacts to extracted items that
+// violate the uniqueness of its primary key or of its unique keys
+type ConstraintPolicy int
+
+const (
+	// KeepLast keeps the last extracted item of the conflicting items. this is
+	// the default policy
+	KeepLast ConstraintPolicy = iota
+
+	// KeepFirst keeps the first extracted item of the conflicting items
+	KeepFirst
+
+	// FailOnViolation fails the reload so the previous generation of the
+	// collection continues to be served
+	FailOnViolation
+
+	// LogViolation keeps the last extracted item of the conflicting items and
+	// logs the violation to the logger of the collection
+	LogViolation
+)
+
+// Violation describes a value of a unique index that is shared by more than
+// one extracted item
+type Violation struct {
+	Index string `json:"index"`
+	Value string `json:"value"`
+
+	// Keys are the distinct primary key values of the conflicting items, in
+	// the order of extraction
+	Keys []string `json:"keys"`
+
+	// Items is the number of the conflicting items
+	Items int `json:"items"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%d items share %s=%q (%s)", v.Items, v.Index, v.Value, strings.Join(v.Keys, ", "))
+}
+
+// ViolationError is returned from a reload that failed due to violations when
+// the collection's policy is FailOnViolation
+type ViolationError struct {
+	Kind       string
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("%d constraint violations in %q; first: %s", len(e.Violations), e.Kind, e.Violations[0])
+}
+
+// OnViolation sets the ConstraintPolicy of the collection
+func OnViolation[T any](policy ConstraintPolicy) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.policy = policy
+	}
+}
+
+// stagedItem is an item that was extracted but not yet loaded to the db
+type stagedItem[T any] struct {
+	key  string
+	item T
+}
+
+// resolve applies the constraint policy of the collection on the provided
+// staged items and returns the items to load along with the owners of the
+// tags of unique indexes that were violated
+func (c *Collection[T]) resolve(staged []stagedItem[T]) (items []stagedItem[T], owners map[string]string, violations []Violation) {
+	items, violations = c.dedupe(staged)
+
+	for _, idx := range c.keys {
+		if !idx.unique {
+			continue
+		}
+
+		var values []string
+		byValue := map[string][]stagedItem[T]{}
+		for _, s := range items {
+			idx.ref(s.item, func(v string) {
+				if _, ok := byValue[v]; !ok {
+					values = append(values, v)
+				}
+
+				byValue[v] = append(byValue[v], s)
+			})
+		}
+
+		for _, v := range values {
+			conflicting := byValue[v]
+			if len(conflicting) < 2 {
+				continue
+			}
+
+			violation := Violation{Index: idx.key, Value: v, Items: len(conflicting)}
+			for _, s := range conflicting {
+				_, _, pkVal, _ := parseKey(s.key)
+				violation.Keys = append(violation.Keys, pkVal)
+			}
+
+			violations = append(violations, violation)
+
+			if owners == nil {
+				owners = map[string]string{}
+			}
+
+			owner := conflicting[len(conflicting)-1]
+			if c.policy == KeepFirst {
+				owner = conflicting[0]
+			}
+
+			owners[mkKey(c.kind, idx.key, v)] = owner.key
+		}
+	}
+
+	return
+}
+
+// dedupe applies the constraint policy of the collection on the staged items
+// of the same primary key
+func (c *Collection[T]) dedupe(staged []stagedItem[T]) (items []stagedItem[T], violations []Violation) {
+	positions := make(map[string]int, len(staged))
+	occurrences := map[string]int{}
+
+	for _, s := range staged {
+		pos, ok := positions[s.key]
+		if !ok {
+			positions[s.key] = len(items)
+			items = append(items, s)
+			continue
+		}
+
+		occurrences[s.key]++
+		if c.policy != KeepFirst {
+			items[pos].item = s.item
+		}
+	}
+
+	for _, s := range items {
+		n, ok := occurrences[s.key]
+		if !ok {
+			continue
+		}
+
+		_, _, v, _ := parseKey(s.key)
+		violations = append(violations, Violation{c.pk.key, v, []string{v}, n + 1})
+	}
+
+	return
+}
+
+// enforce applies the constraint policy of the collection on the provided
+// violations. it fails only if the policy is FailOnViolation
+func (c *Collection[T]) enforce(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	switch c.policy {
+	case FailOnViolation:
+		return &ViolationError{c.kind, violations}
+	case LogViolation:
+		for _, v := range violations {
+			c.log().Warn("constraint violation", "kind", c.kind, "index", v.Index, "value", v.Value, "keys", v.Keys, "items", v.Items)
+		}
+	}
+
+	return nil
+}
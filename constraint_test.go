@@ -0,0 +1,149 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraints(t *testing.T) {
+	books := []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "Dune", "Frank Herbert"},
+		{"1", "Dune Messiah", "Frank Herbert"},
+		{"3", "Children of Dune", "Frank Herbert"},
+	}
+
+	newCol := func(db DB, policy ConstraintPolicy, opts ...CollectionOpt[*book]) (*Collection[*book], Getter[*book], Getter[*book]) {
+		col := NewCollection[*book](db, "books",
+			Extractor(func(load func(in ...*book)) { load(books...) }),
+			OnViolation[*book](policy),
+		).With(opts...)
+
+		return col,
+			col.PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			col.UniqueKey("title", func(b *book, val func(string)) { val(b.Title) })
+	}
+
+	lastWinsViolations := []Violation{
+		{"id", "1", []string{"1"}, 2},
+	}
+
+	t.Run("keep last", func(t *testing.T) {
+		col, byID, byTitle := newCol(NewDB(), KeepLast)
+
+		res, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, 3, res.Items)
+		assert.Equal(t, lastWinsViolations, res.Violations)
+
+		b, ok := byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, "Dune Messiah", b.Title)
+
+		b, ok = byTitle("Dune")
+		assert.True(t, ok)
+		assert.Equal(t, "2", b.ID)
+
+		_, ok = byTitle("Dune Messiah")
+		assert.True(t, ok)
+	})
+
+	t.Run("additional keys aren't unique", func(t *testing.T) {
+		col := NewCollection[*book](NewDB(), "books",
+			Extractor(func(load func(in ...*book)) { load(books...) }),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			AdditionalKey("author", func(b *book, val func(string)) { val(b.Author) }),
+		)
+
+		res, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, lastWinsViolations, res.Violations)
+		assert.Equal(t, 3, col.Count("author", "Frank Herbert"))
+	})
+
+	t.Run("keep first", func(t *testing.T) {
+		col, byID, byTitle := newCol(NewDB(), KeepFirst)
+
+		res, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, 3, res.Items)
+		assert.Equal(t, []Violation{
+			{"id", "1", []string{"1"}, 2},
+			{"title", "Dune", []string{"1", "2"}, 2},
+		}, res.Violations)
+
+		b, ok := byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, "Dune", b.Title)
+
+		for i := 0; i < 10; i++ {
+			b, ok = byTitle("Dune")
+			assert.True(t, ok)
+			assert.Equal(t, "1", b.ID)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		valid := books
+		books = books[3:]
+		defer func() { books = valid }()
+
+		col, byID, _ := newCol(NewDB(), FailOnViolation)
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		books = valid
+
+		res, err := col.Reload(context.Background())
+		var violationErr *ViolationError
+		if !assert.True(t, errors.As(err, &violationErr)) {
+			return
+		}
+
+		assert.Equal(t, lastWinsViolations, violationErr.Violations)
+		assert.Equal(t, lastWinsViolations, res.Violations)
+
+		_, ok := byID("1")
+		assert.False(t, ok)
+
+		_, ok = byID("3")
+		assert.True(t, ok)
+
+		stats := col.Stats()
+		assert.Equal(t, 1, stats.Items)
+		assert.Equal(t, 1, stats.Failures)
+		assert.Equal(t, err.Error(), stats.LastError)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		var buf bytes.Buffer
+		col, byID, _ := newCol(NewDB(), LogViolation, Logger[*book](slog.New(slog.NewTextHandler(&buf, nil))))
+
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		b, ok := byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, "Dune Messiah", b.Title)
+
+		assert.Contains(t, buf.String(), `msg="constraint violation" kind=books index=id value=1 keys=[1] items=2`)
+	})
+}
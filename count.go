@@ -0,0 +1,77 @@
+package inventory
+
+// itemsTag is the tag of the items of the provided kind, which excludes the
+// negative entries and the derivatives that are tagged with the kind as well.
+// it never collides with the tags of the indexes, which have a colon
+func itemsTag(kind string) string {
+	return kind + string(curlyStart) + string(curlyEnd)
+}
+
+// Count returns the number of items with the provided value of the provided
+// index, without fetching them. it is 0 if there is no such index
+func (c *Collection[T]) Count(index, val string) int {
+	if _, ok := c.bitmapIndex(index); ok {
+		return c.countBitmap(index, val)
+	}
+
+	if index == c.pk.key {
+		if _, ok := c.db.Get(mkKey(c.kind, index, val)); ok {
+			return 1
+		}
+
+		return 0
+	}
+
+	return c.db.Count(mkKey(c.kind, index, val))
+}
+
+// Exists reports whether there is an item with the provided value of the
+// provided index
+func (c *Collection[T]) Exists(index, val string) bool {
+	return c.Count(index, val) > 0
+}
+
+// CountAll returns the number of items in the collection
+func (c *Collection[T]) CountAll() int {
+	return c.count()
+}
+
+// CountBy groups the items by their values of the provided index and returns
+// the number of items of each value. it is empty if there is no such index
+func (c *Collection[T]) CountBy(index string) map[string]int {
+	if _, ok := c.bitmapIndex(index); ok {
+		return c.countBitmapBy(index)
+	}
+
+	res := map[string]int{}
+
+	idx, ok := c.indexByName(index)
+	if !ok {
+		return res
+	}
+
+	prefix := c.kind + string(curlyStart) + idx.key + string(colon)
+
+	_ = c.db.View(func(viewer DBViewer) error {
+		if idx.pk {
+			viewer.Iter(itemsTag(c.kind), func(key string, _ func() (any, bool)) bool {
+				if v, ok := indexValue(key, prefix); ok {
+					res[v]++
+				}
+
+				return true
+			})
+
+			return nil
+		}
+
+		indexValues(viewer, c.kind, idx.key, func(v, _ string, keys int) bool {
+			res[v] = keys
+			return true
+		})
+
+		return nil
+	})
+
+	return res
+}
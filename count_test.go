@@ -0,0 +1,75 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	books := []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "Dune Messiah", "Frank Herbert"},
+		{"3", "Foundation", "Isaac Asimov"},
+	}
+
+	col := NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			load(books...)
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		AdditionalKey("title", func(b *book, val func(string)) { val(b.Title) }),
+	)
+	col.MapBy("author", func(b *book, val func(string)) { val(b.Author) })
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, col.CountAll())
+	assert.Equal(t, 2, col.Count("author", "Frank Herbert"))
+	assert.Equal(t, 1, col.Count("id", "3"))
+	assert.Equal(t, 0, col.Count("id", "4"))
+	assert.Equal(t, 0, col.Count("isbn", "0441013597"))
+	assert.True(t, col.Exists("title", "Foundation"))
+	assert.False(t, col.Exists("author", "Brian Herbert"))
+
+	assert.Equal(t, map[string]int{"Frank Herbert": 2, "Isaac Asimov": 1}, col.CountBy("author"))
+	assert.Equal(t, map[string]int{"1": 1, "2": 1, "3": 1}, col.CountBy("id"))
+	assert.Empty(t, col.CountBy("isbn"))
+
+	n, err := col.Select(Where("author", Eq("Frank Herbert"))).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = col.Select(Predicate{}).Filter(func(b *book) bool { return b.Title != "Dune" }).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// negative entries aren't counted
+	col.markAbsent("id", "4", time.Now().Add(time.Minute))
+	assert.Equal(t, 3, col.CountAll())
+
+	books = books[1:]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, col.CountAll())
+	assert.Equal(t, map[string]int{"Frank Herbert": 1, "Isaac Asimov": 1}, col.CountBy("author"))
+
+	// values that are left with no items aren't counted, and are dropped by
+	// the next reload
+	_, err = col.Apply(Change[*book]{Op: Delete, Key: "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Frank Herbert": 1}, col.CountBy("author"))
+	assert.Equal(t, 2, col.db.Count(valuesTag(col.kind, "author")))
+
+	books = books[:1]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, col.db.Count(valuesTag(col.kind, "author")))
+}
@@ -3,6 +3,10 @@ package inventory
 // Getter is a function for fetching 1 item of concrete type by a specific key
 type Getter[T any] func(val string) (T, bool)
 
+// VersionedGetter is a Getter that also returns the version of the collection
+// the item was read from, so derived results can be cached by the version
+type VersionedGetter[T any] func(val string) (t T, version uint64, ok bool)
+
 // Query is a function for fetching list of items of a concrete type by tags
 type Query[T any] func(key string, filters ...func(T) bool) ([]T, error)
 
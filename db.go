@@ -1,6 +1,7 @@
 package inventory
 
 import (
+	"fmt"
 	"maps"
 	"slices"
 	"sync"
@@ -17,7 +18,9 @@ type DB interface {
 	View(func(viewer DBViewer) error) error
 
 	// Update provides atomic read-write access to the db for the scope of
-	// the provided callback.
+	// the provided callback. the changes are discarded if the callback
+	// returns an error or panics, in which case the panic is returned as an
+	// error.
 	Update(func(writer DBWriter) error) error
 
 	// GetOrFill is a nice utility that wraps get, put if not exist and return
@@ -35,6 +38,22 @@ type DBViewer interface {
 
 	// Iter retrieves all the keys under a tag and let you access each item in each key
 	Iter(tag string, fn func(key string, getVal func() (any, bool)) (proceed bool))
+
+	// Tags iterates over all the tags in the db along with the number of keys
+	// under each one of them
+	Tags(fn func(tag string, keys int) (proceed bool))
+
+	// Count returns the number of keys under the provided tag
+	Count(tag string) int
+
+	// Version returns the version of the db. the version is bumped on every
+	// committed update
+	Version() uint64
+
+	// TagVersion returns the version of the last committed update that
+	// affected keys under the provided tag. for a tag with no keys, it is the
+	// version of the last committed update that left any tag with no keys
+	TagVersion(tag string) uint64
 }
 
 // DBWriter represents isolated write access handle to the db
@@ -50,6 +69,9 @@ type DBWriter interface {
 
 	// Invalidate deletes all keys related to the provided tags
 	Invalidate(tags ...string) (deleted []string)
+
+	// Delete deletes the provided keys and removes them from their tags
+	Delete(keys ...string)
 }
 
 func NewDB() DB {
@@ -59,6 +81,9 @@ func NewDB() DB {
 			map[string]map[string]struct{}{},
 			map[string]any{},
 		},
+		versions: versions{
+			tags: map[string]uint64{},
+		},
 	}
 }
 
@@ -68,8 +93,31 @@ type storage struct {
 	items     map[string]any
 }
 
+// versions tracks the version of the db and of each one of its tags that has
+// keys. the versions of tags that are left with no keys are dropped, so they
+// don't pile up with churn, and such tags report the last version in which
+// any tag was emptied instead
+type versions struct {
+	version uint64
+	tags    map[string]uint64
+	emptied uint64
+}
+
+func (v *versions) Version() uint64 {
+	return v.version
+}
+
+func (v *versions) TagVersion(tag string) uint64 {
+	if version, ok := v.tags[tag]; ok {
+		return version
+	}
+
+	return v.emptied
+}
+
 type db struct {
 	storage
+	versions
 
 	t transaction
 
@@ -87,6 +135,14 @@ func (c *db) Invalidate(tags ...string) (deleted []string) {
 	return
 }
 
+func (c *db) Delete(keys ...string) {
+	_ = c.Update(func(writer DBWriter) error {
+		writer.Delete(keys...)
+
+		return nil
+	})
+}
+
 func (c *db) Tag(key string, tags ...string) {
 	_ = c.Update(func(writer DBWriter) error {
 		writer.Tag(key, tags...)
@@ -99,7 +155,7 @@ func (c *db) View(viewFn func(DBViewer) error) (err error) {
 	c.muR.RLock()
 	defer c.muR.RUnlock()
 
-	err = viewFn(cacheView(c.storage))
+	err = viewFn(snapshot{cacheView(c.storage), &c.versions})
 
 	return
 }
@@ -107,8 +163,15 @@ func (c *db) View(viewFn func(DBViewer) error) (err error) {
 func (c *db) Update(updateFn func(DBWriter) error) (err error) {
 	c.muW.Lock()
 	defer c.muW.Unlock()
+	defer c.t.discard()
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicErr(r)
+		}
+	}()
 
 	c.t.origin = &c.storage
+	c.t.versions = &c.versions
 
 	if c.t.additions.items == nil || c.t.additions.keyToTags == nil || c.t.additions.tagToKeys == nil {
 		c.t.additions = storage{
@@ -127,38 +190,58 @@ func (c *db) Update(updateFn func(DBWriter) error) (err error) {
 
 	err = updateFn(&c.t)
 	if err == nil {
-		c.muR.Lock()
-		for k := range c.t.deletions.items {
-			delete(c.storage.items, k)
-		}
-		for k := range c.t.deletions.keyToTags {
-			delete(c.storage.keyToTags, k)
-		}
-		for k := range c.t.deletions.tagToKeys {
-			delete(c.storage.tagToKeys, k)
-		}
+		c.commit()
+	}
 
-		for k, v := range c.t.additions.items {
-			c.storage.items[k] = v
-		}
-		for k, v := range c.t.additions.keyToTags {
-			c.storage.keyToTags[k] = v
-		}
-		for k, v := range c.t.additions.tagToKeys {
-			c.storage.tagToKeys[k] = v
+	return
+}
+
+func (c *db) commit() {
+	c.muR.Lock()
+	defer c.muR.Unlock()
+
+	for k := range c.t.deletions.items {
+		delete(c.storage.items, k)
+	}
+	for k := range c.t.deletions.keyToTags {
+		delete(c.storage.keyToTags, k)
+	}
+	for k := range c.t.deletions.tagToKeys {
+		delete(c.storage.tagToKeys, k)
+	}
+
+	for k, v := range c.t.additions.items {
+		c.storage.items[k] = v
+	}
+	for k, v := range c.t.additions.keyToTags {
+		c.storage.keyToTags[k] = v
+	}
+	for k, v := range c.t.additions.tagToKeys {
+		c.storage.tagToKeys[k] = v
+	}
+
+	c.versions.version++
+	for tag := range c.t.deletions.tagToKeys {
+		if len(c.storage.tagToKeys[tag]) > 0 {
+			c.versions.tags[tag] = c.versions.version
+			continue
 		}
 
-		c.muR.Unlock()
+		delete(c.versions.tags, tag)
+		c.versions.emptied = c.versions.version
+	}
+	for tag := range c.t.additions.tagToKeys {
+		c.versions.tags[tag] = c.versions.version
 	}
+}
 
-	clear(c.t.additions.items)
-	clear(c.t.additions.keyToTags)
-	clear(c.t.additions.tagToKeys)
-	clear(c.t.deletions.items)
-	clear(c.t.deletions.keyToTags)
-	clear(c.t.deletions.tagToKeys)
+// panicErr converts a value recovered from a panic to an error
+func panicErr(r any) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("panic during update: %w", err)
+	}
 
-	return
+	return fmt.Errorf("panic during update: %v", r)
 }
 
 func (c *db) Get(key string) (val any, ok bool) {
@@ -178,6 +261,34 @@ func (c *db) Iter(tag string, fn func(key string, val func() (any, bool)) (proce
 	return
 }
 
+func (c *db) Tags(fn func(tag string, keys int) (proceed bool)) {
+	c.muR.RLock()
+	defer c.muR.RUnlock()
+
+	cacheView(c.storage).Tags(fn)
+}
+
+func (c *db) Count(tag string) int {
+	c.muR.RLock()
+	defer c.muR.RUnlock()
+
+	return cacheView(c.storage).Count(tag)
+}
+
+func (c *db) Version() uint64 {
+	c.muR.RLock()
+	defer c.muR.RUnlock()
+
+	return c.versions.Version()
+}
+
+func (c *db) TagVersion(tag string) uint64 {
+	c.muR.RLock()
+	defer c.muR.RUnlock()
+
+	return c.versions.TagVersion(tag)
+}
+
 func (c *db) Put(key string, val any) {
 	_ = c.Update(func(writer DBWriter) error {
 		writer.Put(key, val)
@@ -240,38 +351,88 @@ func (c cacheView) Iter(tag string, fn func(key string, val func() (any, bool))
 	return
 }
 
+func (c cacheView) Tags(fn func(tag string, keys int) bool) {
+	for tag, keys := range c.tagToKeys {
+		if !fn(tag, len(keys)) {
+			return
+		}
+	}
+}
+
+func (c cacheView) Count(tag string) int {
+	return len(c.tagToKeys[tag])
+}
+
+// snapshot is the view of the committed state of the db
+type snapshot struct {
+	cacheView
+	*versions
+}
+
+// transaction reports the versions of the committed state of the db, as the
+// version of its own changes is determined only when it is committed
 type transaction struct {
+	*versions
+
 	origin    *storage
 	additions storage
 	deletions storage
 }
 
+// discard drops all the changes of the transaction
+func (c *transaction) discard() {
+	clear(c.additions.items)
+	clear(c.additions.keyToTags)
+	clear(c.additions.tagToKeys)
+	clear(c.deletions.items)
+	clear(c.deletions.keyToTags)
+	clear(c.deletions.tagToKeys)
+}
+
 func (c *transaction) Tag(key string, tags ...string) {
-	if _, ok := c.additions.keyToTags[key]; !ok {
-		_, ok = c.origin.keyToTags[key]
-		if ok {
-			c.additions.keyToTags[key] = make(map[string]struct{}, len(c.origin.keyToTags[key]))
-			maps.Copy(c.additions.keyToTags[key], c.origin.keyToTags[key])
-		} else {
-			c.additions.keyToTags[key] = make(map[string]struct{})
-		}
-	}
+	keyTags := c.writableSet(c.additions.keyToTags, c.deletions.keyToTags, c.origin.keyToTags, key)
 
 	for _, tag := range tags {
-		if _, ok := c.additions.tagToKeys[tag]; !ok {
-			c.additions.tagToKeys[tag], ok = c.origin.tagToKeys[tag]
-			if ok {
-				c.additions.tagToKeys[tag] = make(map[string]struct{}, len(c.origin.tagToKeys[tag]))
-				maps.Copy(c.additions.tagToKeys[tag], c.origin.tagToKeys[tag])
-			} else {
-				c.additions.tagToKeys[tag] = make(map[string]struct{})
-			}
-		}
+		c.writableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag)[key] = struct{}{}
+
+		keyTags[tag] = struct{}{}
+	}
+}
+
+// writableSet returns the set under the provided name from the additions. if
+// it isn't there yet, it is copied from the origin unless it was deleted
+func (c *transaction) writableSet(additions, deletions, origin map[string]map[string]struct{}, name string) map[string]struct{} {
+	set, ok := additions[name]
+	if ok {
+		return set
+	}
+
+	if _, deleted := deletions[name]; !deleted {
+		set = make(map[string]struct{}, len(origin[name]))
+		maps.Copy(set, origin[name])
+	} else {
+		set = make(map[string]struct{})
+	}
 
-		c.additions.tagToKeys[tag][key] = struct{}{}
+	additions[name] = set
+
+	return set
+}
 
-		c.additions.keyToTags[key][tag] = struct{}{}
+// readableSet returns the set under the provided name as it is seen by the
+// transaction
+func (c *transaction) readableSet(additions, deletions, origin map[string]map[string]struct{}, name string) (set map[string]struct{}, ok bool) {
+	if set, ok = additions[name]; ok {
+		return
+	}
+
+	if _, deleted := deletions[name]; deleted {
+		return
 	}
+
+	set, ok = origin[name]
+
+	return
 }
 
 func (c *transaction) Get(key string) (val any, ok bool) {
@@ -293,24 +454,12 @@ func (c *transaction) Get(key string) (val any, ok bool) {
 }
 
 func (c *transaction) Iter(tag string, fn func(key string, val func() (any, bool)) bool) {
-	_, deleted := c.deletions.tagToKeys[tag]
-	if deleted {
+	keys, ok := c.readableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag)
+	if !ok {
 		return
 	}
 
-	var keys map[string]struct{}
-	maps.Copy(keys, c.origin.tagToKeys[tag])
-
-	addedKeys, ok := c.additions.tagToKeys[tag]
-	if ok {
-		maps.Copy(keys, addedKeys)
-	}
-
-	for k := range c.additions.tagToKeys[tag] {
-		if _, ok = c.deletions.items[k]; ok {
-			continue
-		}
-
+	for k := range keys {
 		proceed := fn(k, func() (any, bool) {
 			return c.Get(k)
 		})
@@ -323,6 +472,34 @@ func (c *transaction) Iter(tag string, fn func(key string, val func() (any, bool
 	return
 }
 
+func (c *transaction) Tags(fn func(tag string, keys int) bool) {
+	for tag, keys := range c.additions.tagToKeys {
+		if !fn(tag, len(keys)) {
+			return
+		}
+	}
+
+	for tag, keys := range c.origin.tagToKeys {
+		if _, ok := c.additions.tagToKeys[tag]; ok {
+			continue
+		}
+
+		if _, ok := c.deletions.tagToKeys[tag]; ok {
+			continue
+		}
+
+		if !fn(tag, len(keys)) {
+			return
+		}
+	}
+}
+
+func (c *transaction) Count(tag string) int {
+	keys, _ := c.readableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag)
+
+	return len(keys)
+}
+
 func (c *transaction) Put(key string, val any) {
 	c.additions.items[key] = val
 
@@ -331,15 +508,7 @@ func (c *transaction) Put(key string, val any) {
 
 func (c *transaction) Invalidate(tags ...string) (deleted []string) {
 	for _, tag := range tags {
-		_, ok := c.deletions.tagToKeys[tag]
-		if ok {
-			continue
-		}
-
-		keys, ok := c.additions.tagToKeys[tag]
-		if !ok {
-			keys, ok = c.origin.tagToKeys[tag]
-		}
+		keys, ok := c.readableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag)
 		if !ok {
 			continue
 		}
@@ -363,21 +532,33 @@ func (c *transaction) Invalidate(tags ...string) (deleted []string) {
 	return
 }
 
+func (c *transaction) Delete(keys ...string) {
+	for _, key := range keys {
+		c.deleteKey(key)
+	}
+}
+
+// deleteKey deletes the key along with its membership in all of its tags.
+// tags that are left with no keys are deleted as well
 func (c *transaction) deleteKey(key string) {
 	delete(c.additions.items, key)
 	c.deletions.items[key] = nil
 
-	tags, ok := c.additions.keyToTags[key]
-	if !ok {
-		tags, _ = c.origin.keyToTags[key]
-	}
+	tags, _ := c.readableSet(c.additions.keyToTags, c.deletions.keyToTags, c.origin.keyToTags, key)
+	delete(c.additions.keyToTags, key)
+	c.deletions.keyToTags[key] = nil
 
 	for tag := range tags {
-		if _, ok = c.deletions.tagToKeys[tag]; ok {
+		if _, ok := c.readableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag); !ok {
 			continue
 		}
 
-		delete(c.additions.tagToKeys[tag], key)
-		c.deletions.tagToKeys[tag] = nil
+		keys := c.writableSet(c.additions.tagToKeys, c.deletions.tagToKeys, c.origin.tagToKeys, tag)
+		delete(keys, key)
+
+		if len(keys) == 0 {
+			delete(c.additions.tagToKeys, tag)
+			c.deletions.tagToKeys[tag] = nil
+		}
 	}
 }
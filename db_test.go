@@ -138,3 +138,82 @@ func Benchmark_db(b *testing.B) {
 		}
 	})
 }
+
+func Test_dbUpdatePanic(t *testing.T) {
+	c := NewDB()
+	c.Put("foo", "bar")
+	c.Tag("foo", "1")
+
+	err := c.Update(func(writer DBWriter) error {
+		writer.Invalidate("1")
+		writer.Put("bar", "baz")
+		panic("boom")
+	})
+	assert.EqualError(t, err, "panic during update: boom")
+
+	val, ok := c.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", val)
+
+	_, ok = c.Get("bar")
+	assert.False(t, ok)
+
+	err = c.Update(func(writer DBWriter) error {
+		_, ok := writer.Get("bar")
+		assert.False(t, ok)
+
+		writer.Put("baz", "qux")
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	val, ok = c.Get("baz")
+	assert.True(t, ok)
+	assert.Equal(t, "qux", val)
+}
+
+func Test_dbVersions(t *testing.T) {
+	c := NewDB()
+	assert.Equal(t, uint64(0), c.Version())
+
+	c.Put("foo", "bar")
+	c.Tag("foo", "1")
+	assert.Equal(t, uint64(2), c.Version())
+	assert.Equal(t, uint64(2), c.TagVersion("1"))
+
+	c.Put("bar", "baz")
+	c.Tag("bar", "2")
+	assert.Equal(t, uint64(4), c.Version())
+	assert.Equal(t, uint64(2), c.TagVersion("1"))
+	assert.Equal(t, uint64(4), c.TagVersion("2"))
+
+	_ = c.Update(func(writer DBWriter) error {
+		assert.Equal(t, uint64(4), writer.Version())
+		writer.Put("baz", "qux")
+
+		return fmt.Errorf("rollback")
+	})
+	assert.Equal(t, uint64(4), c.Version())
+
+	c.Invalidate("1")
+	assert.Equal(t, uint64(5), c.Version())
+	assert.Equal(t, uint64(5), c.TagVersion("1"))
+	assert.Equal(t, uint64(4), c.TagVersion("2"))
+
+	_ = c.View(func(viewer DBViewer) error {
+		assert.Equal(t, uint64(5), viewer.Version())
+		assert.Equal(t, uint64(4), viewer.TagVersion("2"))
+
+		return nil
+	})
+
+	// the versions of tags that were left with no keys aren't kept
+	for i := 0; i < 10; i++ {
+		c.Tag("bar", fmt.Sprintf("tmp-%d", i))
+		c.Delete("bar")
+	}
+	assert.Len(t, c.(*db).versions.tags, 0)
+	assert.Equal(t, uint64(25), c.TagVersion("tmp-3"))
+	assert.Equal(t, uint64(25), c.TagVersion("2"))
+}
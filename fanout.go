@@ -0,0 +1,91 @@
+package inventory
+
+import (
+	"context"
+	"sync"
+)
+
+// fanout delivers messages in memory to all of its subscribers that accept
+// them. the zero value is ready to use
+type fanout[M any] struct {
+	mu          sync.Mutex
+	subscribers map[*fanoutSubscriber[M]]struct{}
+}
+
+type fanoutSubscriber[M any] struct {
+	accept func(M) bool
+	ch     chan M
+	done   chan struct{}
+}
+
+// publish delivers the provided message to all the subscribers that accept it
+func (f *fanout[M]) publish(ctx context.Context, msg M) error {
+	f.mu.Lock()
+	subscribers := make([]*fanoutSubscriber[M], 0, len(f.subscribers))
+	for sub := range f.subscribers {
+		if sub.accept == nil || sub.accept(msg) {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, sub := range subscribers {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sub.done:
+		case sub.ch <- msg:
+		}
+	}
+
+	return nil
+}
+
+// subscribe calls fn with every published message that is accepted by the
+// provided func until ctx is done. a nil accept func accepts all messages
+func (f *fanout[M]) subscribe(ctx context.Context, accept func(M) bool, fn func(M)) error {
+	sub := &fanoutSubscriber[M]{
+		accept: accept,
+		ch:     make(chan M, 64),
+		done:   make(chan struct{}),
+	}
+
+	f.mu.Lock()
+	if f.subscribers == nil {
+		f.subscribers = map[*fanoutSubscriber[M]]struct{}{}
+	}
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.subscribers, sub)
+		f.mu.Unlock()
+
+		close(sub.done)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-sub.ch:
+			fn(msg)
+		}
+	}
+}
+
+// accepted returns the number of the subscribers that accept the provided
+// message
+func (f *fanout[M]) accepted(msg M) (n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subscribers {
+		if sub.accept == nil || sub.accept(msg) {
+			n++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,117 @@
+package inventory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fields maps names to the exported fields of T, which is either a struct or
+// a pointer to a struct, in order to decode items of T from sources that are
+// made of named columns
+type fields[T any] struct {
+	typ   reflect.Type
+	ptr   bool
+	named map[string][]int
+}
+
+// newFields creates the fields of T. the name of a field is taken from the
+// provided struct tag and defaults to the name of the field. names are matched
+// regardless of case and underscores
+func newFields[T any](tag string) (f *fields[T], err error) {
+	f = &fields[T]{
+		typ:   reflect.TypeOf((*T)(nil)).Elem(),
+		named: map[string][]int{},
+	}
+
+	if f.typ.Kind() == reflect.Pointer {
+		f.ptr = true
+		f.typ = f.typ.Elem()
+	}
+
+	if f.typ.Kind() != reflect.Struct {
+		err = fmt.Errorf("%T is not a struct or a pointer to a struct", *new(T))
+		return
+	}
+
+	for _, field := range reflect.VisibleFields(f.typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if v, ok := field.Tag.Lookup(tag); ok {
+			name, _, _ = strings.Cut(v, ",")
+			if name == "-" {
+				continue
+			}
+		}
+
+		f.named[normalizeName(name)] = field.Index
+	}
+
+	return
+}
+
+// resolve returns the index of the field of every one of the provided names.
+// mapping overrides the field of a name by the name of a field in T
+func (f *fields[T]) resolve(names []string, mapping map[string]string) (res [][]int, err error) {
+	res = make([][]int, len(names))
+
+	for i, name := range names {
+		if fieldName, ok := mapping[name]; ok {
+			field, ok := f.typ.FieldByName(fieldName)
+			if !ok || !field.IsExported() {
+				return nil, fmt.Errorf("%s has no exported field %q for %q", f.typ, fieldName, name)
+			}
+
+			res[i] = field.Index
+			continue
+		}
+
+		index, ok := f.named[normalizeName(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field for %q", f.typ, name)
+		}
+
+		res[i] = index
+	}
+
+	return
+}
+
+// new allocates a new item of T and returns it along with its settable
+// struct value
+func (f *fields[T]) new() (item func() T, v reflect.Value) {
+	ptr := reflect.New(f.typ)
+
+	return func() T {
+		if f.ptr {
+			return ptr.Interface().(T)
+		}
+
+		return ptr.Elem().Interface().(T)
+	}, ptr.Elem()
+}
+
+// field returns the settable field of the provided struct value by index,
+// allocating nil embedded pointers along the way
+func (f *fields[T]) field(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
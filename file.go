@@ -0,0 +1,414 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of the files that are decoded by a FileExtractor
+type Format int
+
+const (
+	// FormatAuto detects the format of every file by its extension
+	FormatAuto Format = iota
+
+	// FormatJSON is a JSON array of items or a single JSON item
+	FormatJSON
+
+	// FormatNDJSON is a JSON item per line
+	FormatNDJSON
+
+	// FormatCSV is a CSV with a header row. every column is decoded into the
+	// field of the item that is named by the `csv` struct tag or by its name,
+	// regardless of case and underscores
+	FormatCSV
+
+	// FormatYAML is a stream of YAML documents, each one is either a sequence
+	// of items or a single item
+	FormatYAML
+)
+
+var formatByExt = map[string]Format{
+	".json":   FormatJSON,
+	".ndjson": FormatNDJSON,
+	".jsonl":  FormatNDJSON,
+	".csv":    FormatCSV,
+	".yaml":   FormatYAML,
+	".yml":    FormatYAML,
+}
+
+// NewFileExtractor creates a FileExtractor of T that decodes the file, or all
+// the files under the directory, of the provided path
+func NewFileExtractor[T any](path string, opts ...FileOpt) *FileExtractor[T] {
+	x := &FileExtractor[T]{
+		path: path,
+		fileOptions: fileOptions{
+			comma: ',',
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&x.fileOptions)
+	}
+
+	return x
+}
+
+// FileExtractor extracts items of T from a file or from a directory of files.
+// its Extract method is meant to be used as the Source of a Collection:
+//
+//	x := NewFileExtractor[*country]("/etc/app/countries.csv")
+//	countries := NewCollection[*country](db, "countries", Source(x.Extract), ...)
+type FileExtractor[T any] struct {
+	fileOptions
+
+	path string
+}
+
+// FileOpt is an option for instrumenting a FileExtractor
+type FileOpt func(*fileOptions)
+
+type fileOptions struct {
+	format Format
+	comma  rune
+}
+
+// FileFormat sets the format of the files. by default, the format of every
+// file is detected by its extension and files of a directory with unknown
+// extensions are skipped
+func FileFormat(format Format) FileOpt {
+	return func(o *fileOptions) {
+		o.format = format
+	}
+}
+
+// CSVComma sets the field delimiter of CSV files. the default is ','
+func CSVComma(comma rune) FileOpt {
+	return func(o *fileOptions) {
+		o.comma = comma
+	}
+}
+
+// Path returns the path of the file or the directory the items are extracted
+// from
+func (x *FileExtractor[T]) Path() string {
+	return x.path
+}
+
+// Extract decodes all the items from the file, or from all the files under
+// the directory, of the FileExtractor. the files of a directory are decoded
+// in lexical order
+func (x *FileExtractor[T]) Extract(ctx context.Context, load func(in ...T)) error {
+	info, err := os.Stat(x.path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return x.extractFile(x.path, x.formatOf(x.path), load)
+	}
+
+	return filepath.WalkDir(x.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		format := x.formatOf(path)
+		if format == FormatAuto {
+			return nil
+		}
+
+		return x.extractFile(path, format, load)
+	})
+}
+
+func (x *FileExtractor[T]) formatOf(path string) Format {
+	if x.format != FormatAuto {
+		return x.format
+	}
+
+	return formatByExt[strings.ToLower(filepath.Ext(path))]
+}
+
+func (x *FileExtractor[T]) extractFile(path string, format Format, load func(in ...T)) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var items []T
+	switch format {
+	case FormatJSON:
+		items, err = decodeJSON[T](f)
+	case FormatNDJSON:
+		items, err = decodeNDJSON[T](f)
+	case FormatCSV:
+		items, err = decodeCSV[T](f, x.comma)
+	case FormatYAML:
+		items, err = decodeYAML[T](f)
+	default:
+		err = fmt.Errorf("unknown format")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	if len(items) > 0 {
+		load(items...)
+	}
+
+	return
+}
+
+// decodeJSON decodes either a JSON array of items or a single JSON item
+func decodeJSON[T any](r io.Reader) (items []T, err error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(src))
+	if !bytes.HasPrefix(bytes.TrimSpace(src), []byte{'['}) {
+		var item T
+		if err = dec.Decode(&item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineAt(src, dec.InputOffset()), err)
+		}
+
+		return []T{item}, nil
+	}
+
+	if _, err = dec.Token(); err != nil {
+		return
+	}
+
+	for record := 1; dec.More(); record++ {
+		offset := dec.InputOffset()
+
+		var item T
+		if err = dec.Decode(&item); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				offset = syntaxErr.Offset
+			}
+
+			return nil, fmt.Errorf("record %d (line %d): %w", record, lineAt(src, offset), err)
+		}
+
+		items = append(items, item)
+	}
+
+	if _, err = dec.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", lineAt(src, dec.InputOffset()), err)
+	}
+
+	return
+}
+
+// lineAt returns the line of the provided offset in src, skipping the
+// whitespace and the separators that follow the offset
+func lineAt(src []byte, offset int64) int {
+	offset = min(offset, int64(len(src)))
+	for offset < int64(len(src)) && strings.ContainsRune(" \t\r\n,", rune(src[offset])) {
+		offset++
+	}
+
+	return bytes.Count(src[:min(offset, int64(len(src)))], []byte{'\n'}) + 1
+}
+
+// decodeNDJSON decodes a JSON item per line, skipping blank lines
+func decodeNDJSON[T any](r io.Reader) (items []T, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var item T
+		if err = json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		items = append(items, item)
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+// decodeCSV decodes a CSV with a header row that maps the columns to the
+// fields of the items
+func decodeCSV[T any](r io.Reader, comma rune) (items []T, err error) {
+	f, err := newFields[T]("csv")
+	if err != nil {
+		return
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+
+		return
+	}
+
+	header = slices.Clone(header)
+	index, err := f.resolve(header, nil)
+	if err != nil {
+		return
+	}
+
+	for record := 1; ; record++ {
+		var values []string
+		values, err = reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", record, err)
+		}
+
+		item, v := f.new()
+		for i, value := range values {
+			if err = setText(f.field(v, index[i]), value); err != nil {
+				line, _ := reader.FieldPos(i)
+				return nil, fmt.Errorf("record %d (line %d) column %q: %w", record, line, header[i], err)
+			}
+		}
+
+		items = append(items, item())
+	}
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setText sets the provided field by parsing the provided text according to
+// the type of the field
+func setText(field reflect.Value, text string) (err error) {
+	if field.Kind() == reflect.Pointer {
+		if text == "" {
+			return
+		}
+
+		ptr := reflect.New(field.Type().Elem())
+		if err = setText(ptr.Elem(), text); err != nil {
+			return
+		}
+
+		field.Set(ptr)
+
+		return
+	}
+
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	if field.Kind() != reflect.String && text == "" {
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(text)
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if field.Type() == durationType {
+			var d time.Duration
+			d, err = time.ParseDuration(text)
+			n = int64(d)
+		} else {
+			n, err = strconv.ParseInt(text, 10, field.Type().Bits())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, field.Type().Bits())
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		n, err = strconv.ParseFloat(text, field.Type().Bits())
+		field.SetFloat(n)
+	default:
+		err = fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return
+}
+
+// decodeYAML decodes a stream of YAML documents, each one is either a
+// sequence of items or a single item
+func decodeYAML[T any](r io.Reader) (items []T, err error) {
+	dec := yaml.NewDecoder(r)
+
+	for {
+		var doc yaml.Node
+		if err = dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return items, nil
+			}
+
+			return nil, err
+		}
+
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		nodes := []*yaml.Node{doc.Content[0]}
+		if doc.Content[0].Kind == yaml.SequenceNode {
+			nodes = doc.Content[0].Content
+		}
+
+		for _, node := range nodes {
+			var item T
+			if err = node.Decode(&item); err != nil {
+				return nil, fmt.Errorf("record %d (line %d): %w", len(items)+1, node.Line, err)
+			}
+
+			items = append(items, item)
+		}
+	}
+}
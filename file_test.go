@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileExtractor(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755)) {
+			t.FailNow()
+		}
+
+		if !assert.NoError(t, os.WriteFile(path, []byte(content), 0o644)) {
+			t.FailNow()
+		}
+
+		return path
+	}
+
+	extract := func(x *FileExtractor[*pagedBook]) (res []*pagedBook, err error) {
+		err = x.Extract(context.Background(), func(in ...*pagedBook) {
+			res = append(res, in...)
+		})
+
+		return
+	}
+
+	dune := &pagedBook{book{"1", "Dune", "Frank Herbert"}, 412}
+	messiah := &pagedBook{book{"2", "Dune Messiah", "Frank Herbert"}, 256}
+
+	t.Run("json", func(t *testing.T) {
+		res, err := extract(NewFileExtractor[*pagedBook](write("books/a.json", `[
+			{"id": "1", "title": "Dune", "author": "Frank Herbert", "Pages": 412},
+			{"id": "2", "title": "Dune Messiah", "author": "Frank Herbert", "Pages": 256}
+		]`)))
+		assert.NoError(t, err)
+		assert.Equal(t, []*pagedBook{dune, messiah}, res)
+
+		res, err = extract(NewFileExtractor[*pagedBook](write("single.json", `{"id": "1", "title": "Dune", "author": "Frank Herbert", "Pages": 412}`)))
+		assert.NoError(t, err)
+		assert.Equal(t, []*pagedBook{dune}, res)
+
+		_, err = extract(NewFileExtractor[*pagedBook](write("bad.json", `[
+			{"id": "1", "title": "Dune"},
+			{"id": "2", "title": "Dune Messiah", "Pages": "many"}
+		]`)))
+		assert.ErrorContains(t, err, "bad.json: record 2 (line 3): json: cannot unmarshal string")
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		res, err := extract(NewFileExtractor[*pagedBook](write("books/b.ndjson", `{"id": "1", "title": "Dune", "author": "Frank Herbert", "Pages": 412}
+
+{"id": "2", "title": "Dune Messiah", "author": "Frank Herbert", "Pages": 256}
+`)))
+		assert.NoError(t, err)
+		assert.Equal(t, []*pagedBook{dune, messiah}, res)
+
+		_, err = extract(NewFileExtractor[*pagedBook](write("bad.jsonl", "{\"id\": \"1\"}\n{\"id\": 2}\n")))
+		assert.ErrorContains(t, err, "bad.jsonl: line 2: json: cannot unmarshal number")
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		res, err := extract(NewFileExtractor[*pagedBook](write("books/c.csv", "id,title,author,pages\n1,Dune,Frank Herbert,412\n2,Dune Messiah,Frank Herbert,256\n")))
+		assert.NoError(t, err)
+		assert.Equal(t, []*pagedBook{dune, messiah}, res)
+
+		res, err = extract(NewFileExtractor[*pagedBook](write("books.tsv", "ID\tTitle\n1\tDune\n"), FileFormat(FormatCSV), CSVComma('\t')))
+		assert.NoError(t, err)
+		assert.Equal(t, []*pagedBook{{book: book{ID: "1", Title: "Dune"}}}, res)
+
+		_, err = extract(NewFileExtractor[*pagedBook](write("bad.csv", "id,pages\n1,412\n2,many\n")))
+		assert.ErrorContains(t, err, `bad.csv: record 2 (line 3) column "pages": strconv.ParseInt`)
+
+		_, err = extract(NewFileExtractor[*pagedBook](write("unknown.csv", "id,year\n1,1965\n")))
+		assert.ErrorContains(t, err, `unknown.csv: inventory.pagedBook has no field for "year"`)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		x := NewFileExtractor[book](write("d.yaml", `
+- id: "1"
+  title: Dune
+  author: Frank Herbert
+---
+id: "2"
+title: Dune Messiah
+author: Frank Herbert
+`))
+
+		var res []book
+		err := x.Extract(context.Background(), func(in ...book) { res = append(res, in...) })
+		assert.NoError(t, err)
+		assert.Equal(t, []book{dune.book, messiah.book}, res)
+
+		x = NewFileExtractor[book](write("bad.yml", `
+- id: "1"
+- id: ["2"]
+`))
+		err = x.Extract(context.Background(), func(in ...book) {})
+		assert.ErrorContains(t, err, "bad.yml: record 2 (line 3)")
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		write("books/README.md", "# not a book")
+
+		col := NewCollection[*pagedBook](NewDB(), "books",
+			Source(NewFileExtractor[*pagedBook](filepath.Join(dir, "books")).Extract),
+			PrimaryKey("id", func(b *pagedBook, val func(string)) { val(b.ID) }),
+		)
+
+		res, err := col.Reload(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Items)
+		assert.Equal(t, []Violation{
+			{"id", "1", []string{"1"}, 3},
+			{"id", "2", []string{"2"}, 3},
+		}, res.Violations)
+
+		b, ok := col.GetBy("id")("2")
+		assert.True(t, ok)
+		assert.Equal(t, messiah, b)
+	})
+}
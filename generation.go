@@ -0,0 +1,212 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Generation describes a committed load of all the items of a Collection
+type Generation struct {
+	ID       int       `json:"id"`
+	LoadedAt time.Time `json:"loadedAt"`
+	Items    int       `json:"items"`
+}
+
+// generation is a committed load of a collection that is kept in order to be
+// able to roll back to it
+type generation[T any] struct {
+	Generation
+
+	items  []stagedItem[T]
+	owners map[string]string
+}
+
+// KeepGenerations sets the number of the last committed generations of the
+// collection, including the current one, that are kept in order to be able to
+// roll back to them
+func KeepGenerations[T any](n int) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.keepGens = n
+	}
+}
+
+// Generations returns the kept generations of the collection, from the oldest
+// to the current one
+func (c *Collection[T]) Generations() (res []Generation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, gen := range c.generations {
+		res = append(res, gen.Generation)
+	}
+
+	return
+}
+
+// Rollback reloads the generation that preceded the current one from memory
+// and discards the current one. it requires KeepGenerations of at least 2
+func (c *Collection[T]) Rollback(ctx context.Context) (res Generation, err error) {
+	// a rollback is serialized with reloads, so the generation that it
+	// discards is the one that is committed
+	c.reloading.Lock()
+	defer c.reloading.Unlock()
+
+	c.mu.Lock()
+	if len(c.generations) < 2 {
+		c.mu.Unlock()
+		err = fmt.Errorf("no previous generation of %q to roll back to", c.kind)
+		return
+	}
+
+	current := c.generations[len(c.generations)-1]
+	prev := c.generations[len(c.generations)-2]
+	c.mu.Unlock()
+
+	err = c.db.Update(func(writer DBWriter) (err error) {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		c.invalidate(writer)
+		for _, s := range prev.items {
+			c.loadItem(writer, s.key, s.item, prev.owners)
+		}
+
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	for i, gen := range c.generations {
+		if gen == current {
+			c.generations = c.generations[:i]
+			break
+		}
+	}
+	c.stats.Generation = prev.ID
+	c.mu.Unlock()
+
+	c.committed(nil, time.Now())
+	c.afterFullReload()
+
+	res = prev.Generation
+
+	return
+}
+
+// committed is called after every committed change of the items of the
+// collection. it keeps the provided generation, if any, updates the stats and
+// calls the funcs that are registered to be called after reloads
+func (c *Collection[T]) committed(gen *generation[T], loadedAt time.Time) {
+	n := c.count()
+
+	c.mu.Lock()
+	if gen != nil {
+		c.remember(gen, loadedAt, n)
+	}
+	c.stats.Items = n
+	c.mu.Unlock()
+
+	c.afterReload()
+}
+
+// remember keeps the provided committed generation of n items according to
+// the number of generations the collection keeps. it is called with c.mu held
+func (c *Collection[T]) remember(gen *generation[T], loadedAt time.Time, n int) {
+	c.lastGen++
+	gen.Generation = Generation{c.lastGen, loadedAt, n}
+	c.stats.Generation = c.lastGen
+
+	if c.keepGens <= 0 {
+		return
+	}
+
+	c.generations = append(c.generations, gen)
+	if n := len(c.generations) - c.keepGens; n > 0 {
+		clear(c.generations[:n])
+		c.generations = c.generations[n:]
+	}
+}
+
+// patch returns the generation that results from replacing the items of the
+// provided keys in the current generation by the provided delta. if there is
+// no current generation, it is taken from the db
+func (c *Collection[T]) patch(keys []string, delta *generation[T]) *generation[T] {
+	c.mu.Lock()
+	keep := c.keepGens > 0
+	var current *generation[T]
+	if n := len(c.generations); n > 0 {
+		current = c.generations[n-1]
+	}
+	c.mu.Unlock()
+
+	if !keep {
+		return &generation[T]{}
+	}
+
+	if current == nil {
+		return c.snapshot()
+	}
+
+	replaced := make(map[string]struct{}, len(keys)+len(delta.items))
+	for _, key := range keys {
+		replaced[key] = struct{}{}
+	}
+	for _, s := range delta.items {
+		replaced[s.key] = struct{}{}
+	}
+
+	gen := &generation[T]{items: make([]stagedItem[T], 0, len(current.items)+len(delta.items))}
+	for _, s := range current.items {
+		if _, ok := replaced[s.key]; !ok {
+			gen.items = append(gen.items, s)
+		}
+	}
+	gen.items = append(gen.items, delta.items...)
+
+	for tag, owner := range current.owners {
+		if _, ok := replaced[owner]; !ok {
+			if gen.owners == nil {
+				gen.owners = map[string]string{}
+			}
+
+			gen.owners[tag] = owner
+		}
+	}
+	for tag, owner := range delta.owners {
+		if gen.owners == nil {
+			gen.owners = map[string]string{}
+		}
+
+		gen.owners[tag] = owner
+	}
+
+	return gen
+}
+
+// snapshot returns the generation of the items that are currently in the db
+func (c *Collection[T]) snapshot() *generation[T] {
+	gen := &generation[T]{}
+
+	_ = c.db.View(func(viewer DBViewer) error {
+		viewer.Iter(itemsTag(c.kind), func(key string, getVal func() (any, bool)) bool {
+			if i, ok := getVal(); ok {
+				if t, ok := i.(T); ok {
+					gen.items = append(gen.items, stagedItem[T]{key, t})
+				}
+			}
+
+			return true
+		})
+
+		return nil
+	})
+
+	sort.Slice(gen.items, func(i, j int) bool { return gen.items[i].key < gen.items[j].key })
+
+	return gen
+}
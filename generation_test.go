@@ -0,0 +1,108 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerations(t *testing.T) {
+	var (
+		books   []*book
+		explode bool
+	)
+
+	col := NewCollection[*book](NewDB(), "books",
+		Extractor(func(load func(in ...*book)) {
+			load(books[0])
+			if explode {
+				panic("source went away")
+			}
+			load(books[1:]...)
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		KeepGenerations[*book](2),
+	)
+	byID := col.GetBy("id")
+
+	var reloaded int
+	col.reloaded = append(col.reloaded, func() { reloaded++ })
+
+	_, err := col.Rollback(context.Background())
+	assert.EqualError(t, err, `no previous generation of "books" to roll back to`)
+
+	books = []*book{{"1", "Dune", "Frank Herbert"}, {"2", "Dune Messiah", "Frank Herbert"}}
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	explode = true
+	_, err = col.Reload(context.Background())
+	assert.EqualError(t, err, "panic during update: source went away")
+
+	_, ok := byID("2")
+	assert.True(t, ok)
+
+	explode = false
+	books = []*book{{"1", "Dune", "Frank Herbert"}, {"3", "Children of Dune", "Frank Herbert"}}
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	books = []*book{{"4", "God Emperor of Dune", "Frank Herbert"}}
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	gens := col.Generations()
+	if assert.Len(t, gens, 2) {
+		assert.Equal(t, 2, gens[0].ID)
+		assert.Equal(t, 2, gens[0].Items)
+		assert.Equal(t, 3, gens[1].ID)
+		assert.Equal(t, 1, gens[1].Items)
+	}
+
+	gen, err := col.Rollback(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, gen.ID)
+
+	_, ok = byID("4")
+	assert.False(t, ok)
+
+	_, ok = byID("3")
+	assert.True(t, ok)
+
+	assert.Equal(t, 2, col.Stats().Generation)
+	assert.Equal(t, 2, col.Stats().Items)
+	assert.Len(t, col.Generations(), 1)
+
+	// a rollback is followed by the same funcs as a reload
+	assert.Equal(t, 4, reloaded)
+
+	_, err = col.Rollback(context.Background())
+	assert.Error(t, err)
+
+	// a rollback waits for the reload in progress
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	col.reloading.Lock()
+	go func() {
+		defer close(done)
+
+		_, err := col.Rollback(context.Background())
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("rollback didn't wait for the reload")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	col.reloading.Unlock()
+	<-done
+	assert.Len(t, col.Generations(), 1)
+}
@@ -0,0 +1,348 @@
+package inventory
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Rect is a bounding box of coordinates in degrees. a point is a Rect whose
+// min and max are equal. boxes that cross the antimeridian aren't supported
+type Rect struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// Point returns the Rect of the provided coordinates
+func Point(lat, lon float64) Rect {
+	return Rect{lat, lon, lat, lon}
+}
+
+// geoFn emits the locations of an item
+type geoFn[T any] func(item T, loc func(Rect))
+
+func (r Rect) union(o Rect) Rect {
+	return Rect{min(r.MinLat, o.MinLat), min(r.MinLon, o.MinLon), max(r.MaxLat, o.MaxLat), max(r.MaxLon, o.MaxLon)}
+}
+
+func (r Rect) intersects(o Rect) bool {
+	return r.MinLat <= o.MaxLat && o.MinLat <= r.MaxLat && r.MinLon <= o.MaxLon && o.MinLon <= r.MaxLon
+}
+
+func (r Rect) center() (lat, lon float64) {
+	return (r.MinLat + r.MaxLat) / 2, (r.MinLon + r.MaxLon) / 2
+}
+
+// distance returns the great-circle distance in meters between the provided
+// point and the closest point of the rect. a point within the longitudes of
+// the rect is closest to it along its meridian, and any other point is
+// closest to one of the meridian edges of the rect
+func (r Rect) distance(lat, lon float64) float64 {
+	if lon >= r.MinLon && lon <= r.MaxLon {
+		return math.Abs(lat-min(max(lat, r.MinLat), r.MaxLat)) * math.Pi / 180 * earthRadius
+	}
+
+	return min(r.edgeDistance(lat, lon, r.MinLon), r.edgeDistance(lat, lon, r.MaxLon))
+}
+
+// edgeDistance returns the distance in meters between the provided point and
+// the closest point of the edge of the rect along the provided meridian
+func (r Rect) edgeDistance(lat, lon, meridian float64) float64 {
+	rad := math.Pi / 180
+	dLon := math.Mod(lon-meridian+540, 360) - 180
+
+	// the latitude of the point of the meridian that is closest to the point.
+	// past 90 degrees of longitude, it is the pole of the point's hemisphere
+	closest := math.Copysign(90, lat)
+	if math.Abs(dLon) < 90 {
+		closest = math.Atan2(math.Tan(lat*rad), math.Cos(dLon*rad)) / rad
+	}
+
+	// the distance grows along the meridian away from the closest point
+	return haversine(lat, lon, min(max(closest, r.MinLat), r.MaxLat), meridian)
+}
+
+const earthRadius = 6371e3
+
+// haversine returns the great-circle distance in meters between two points
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat, dLon := (lat2-lat1)*rad, (lon2-lon1)*rad
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// geoIndex is a spatial index of the collection. it is an R-tree that is bulk
+// loaded from the items of the collection once per version of the collection
+type geoIndex[T any] struct {
+	name string
+	ref  geoFn[T]
+
+	mu      sync.Mutex
+	version uint64
+	root    *geoNode
+}
+
+// geoEntry is the location of an item
+type geoEntry struct {
+	rect Rect
+	key  string
+}
+
+type geoNode struct {
+	rect     Rect
+	children []*geoNode
+	entries  []geoEntry
+}
+
+// geoNodeSize is the max number of children or entries of a node
+const geoNodeSize = 16
+
+// GeoIndex adds a spatial index of the collection over the locations that are
+// emitted by the provided func, which is queried by Nearest and Within
+func GeoIndex[T any](name string, loc func(item T, loc func(Rect))) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.geos = append(c.geos, &geoIndex[T]{name: name, ref: loc})
+	}
+}
+
+// Nearest returns the n items of the provided spatial index that are closest
+// to the provided point, from the closest
+func (c *Collection[T]) Nearest(index string, lat, lon float64, n int) ([]T, error) {
+	return c.matchGeo(index, func(root *geoNode) (keys []string) {
+		q := &geoQueue{}
+		heap.Push(q, geoItem{node: root, dist: root.rect.distance(lat, lon)})
+
+		seen := map[string]struct{}{}
+		for q.Len() > 0 && len(keys) < n {
+			it := heap.Pop(q).(geoItem)
+			switch {
+			case it.node == nil:
+				if _, ok := seen[it.key]; !ok {
+					seen[it.key] = struct{}{}
+					keys = append(keys, it.key)
+				}
+			default:
+				for _, child := range it.node.children {
+					heap.Push(q, geoItem{node: child, dist: child.rect.distance(lat, lon)})
+				}
+
+				for _, e := range it.node.entries {
+					heap.Push(q, geoItem{key: e.key, dist: e.rect.distance(lat, lon)})
+				}
+			}
+		}
+
+		return
+	})
+}
+
+// Within returns the items of the provided spatial index whose locations
+// intersect the provided bounding box
+func (c *Collection[T]) Within(index string, box Rect) ([]T, error) {
+	return c.matchGeo(index, func(root *geoNode) (keys []string) {
+		var search func(n *geoNode)
+		search = func(n *geoNode) {
+			if !n.rect.intersects(box) {
+				return
+			}
+
+			for _, child := range n.children {
+				search(child)
+			}
+
+			for _, e := range n.entries {
+				if e.rect.intersects(box) {
+					keys = append(keys, e.key)
+				}
+			}
+		}
+
+		search(root)
+
+		sort.Strings(keys)
+
+		return slices.Compact(keys)
+	})
+}
+
+// matchGeo finds the keys of the items of the provided spatial index by the
+// provided func and returns the items, all from the same snapshot of the db
+func (c *Collection[T]) matchGeo(index string, find func(root *geoNode) []string) (res []T, err error) {
+	i := slices.IndexFunc(c.geos, func(idx *geoIndex[T]) bool { return idx.name == index })
+	if i < 0 {
+		return nil, fmt.Errorf("collection %q has no spatial index %q", c.kind, index)
+	}
+
+	idx := c.geos[i]
+
+	err = c.db.View(func(viewer DBViewer) error {
+		root, err := idx.build(viewer, c.kind)
+		if err != nil || root == nil {
+			return err
+		}
+
+		for _, key := range find(root) {
+			i, ok := viewer.Get(key)
+			if !ok {
+				continue
+			}
+
+			t, ok := i.(T)
+			if !ok {
+				return fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+			}
+
+			res = append(res, t)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// build returns the R-tree of the items of the collection in the provided
+// viewer, which is rebuilt if the collection has changed since it was last
+// built
+func (idx *geoIndex[T]) build(viewer DBViewer, kind string) (root *geoNode, err error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	version := viewer.TagVersion(itemsTag(kind))
+	if idx.version == version && idx.root != nil {
+		return idx.root, nil
+	}
+
+	var entries []geoEntry
+	viewer.Iter(itemsTag(kind), func(key string, getVal func() (any, bool)) bool {
+		i, ok := getVal()
+		if !ok {
+			return true
+		}
+
+		t, ok := i.(T)
+		if !ok {
+			err = fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+			return false
+		}
+
+		idx.ref(t, func(r Rect) {
+			entries = append(entries, geoEntry{r, key})
+		})
+
+		return true
+	})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	idx.root, idx.version = packGeo(entries), version
+
+	return idx.root, nil
+}
+
+// packGeo bulk loads an R-tree of the provided entries by sort-tile-recursive
+// packing
+func packGeo(entries []geoEntry) *geoNode {
+	var nodes []*geoNode
+	for _, group := range tiles(len(entries), func(i int) Rect { return entries[i].rect }, func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	}) {
+		n := &geoNode{entries: entries[group[0]:group[1]], rect: entries[group[0]].rect}
+		for _, e := range n.entries {
+			n.rect = n.rect.union(e.rect)
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	for len(nodes) > 1 {
+		level := nodes
+		nodes = nil
+
+		for _, group := range tiles(len(level), func(i int) Rect { return level[i].rect }, func(i, j int) {
+			level[i], level[j] = level[j], level[i]
+		}) {
+			n := &geoNode{children: level[group[0]:group[1]], rect: level[group[0]].rect}
+			for _, child := range n.children {
+				n.rect = n.rect.union(child.rect)
+			}
+
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes[0]
+}
+
+// tiles sorts n rects into vertical slices by their longitudes and every
+// slice by their latitudes, and returns the ranges of the nodes of the next
+// level
+func tiles(n int, rect func(i int) Rect, swap func(i, j int)) (groups [][2]int) {
+	nodes := (n + geoNodeSize - 1) / geoNodeSize
+	slice := int(math.Ceil(math.Sqrt(float64(nodes)))) * geoNodeSize
+
+	sortRange := func(from, to int, key func(r Rect) float64) {
+		sort.Sort(rangeSorter{from, to, func(i, j int) bool { return key(rect(i)) < key(rect(j)) }, swap})
+	}
+
+	sortRange(0, n, func(r Rect) float64 { _, lon := r.center(); return lon })
+
+	for from := 0; from < n; from += slice {
+		to := min(from+slice, n)
+		sortRange(from, to, func(r Rect) float64 { lat, _ := r.center(); return lat })
+
+		for i := from; i < to; i += geoNodeSize {
+			groups = append(groups, [2]int{i, min(i+geoNodeSize, to)})
+		}
+	}
+
+	return
+}
+
+// rangeSorter sorts a range of a collection
+type rangeSorter struct {
+	from, to int
+	less     func(i, j int) bool
+	swap     func(i, j int)
+}
+
+func (s rangeSorter) Len() int           { return s.to - s.from }
+func (s rangeSorter) Less(i, j int) bool { return s.less(s.from+i, s.from+j) }
+func (s rangeSorter) Swap(i, j int)      { s.swap(s.from+i, s.from+j) }
+
+// geoItem is either a node or an entry in the queue of a nearest search
+type geoItem struct {
+	node *geoNode
+	key  string
+	dist float64
+}
+
+type geoQueue []geoItem
+
+func (q geoQueue) Len() int { return len(q) }
+
+// Less orders entries before nodes of the same distance, so they are
+// returned as soon as possible
+func (q geoQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+
+	return q[i].node == nil && q[j].node != nil
+}
+
+func (q geoQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *geoQueue) Push(x any)   { *q = append(*q, x.(geoItem)) }
+
+func (q *geoQueue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return it
+}
@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type store struct {
+	ID       string
+	Lat, Lon float64
+}
+
+func TestGeoIndex(t *testing.T) {
+	stores := []*store{
+		{"tel-aviv", 32.0853, 34.7818},
+		{"jerusalem", 31.7683, 35.2137},
+		{"haifa", 32.7940, 34.9896},
+		{"eilat", 29.5577, 34.9519},
+		{"london", 51.5072, -0.1276},
+		{"paris", 48.8566, 2.3522},
+	}
+
+	// a grid of stores, so the tree has more than a single level
+	for lat := 0; lat < 10; lat++ {
+		for lon := 0; lon < 10; lon++ {
+			stores = append(stores, &store{fmt.Sprintf("grid-%d-%d", lat, lon), 10 + float64(lat), 10 + float64(lon)})
+		}
+	}
+
+	col := NewCollection[*store](NewDB(), "stores",
+		Source(func(ctx context.Context, load func(in ...*store)) error {
+			load(stores...)
+			return nil
+		}),
+		PrimaryKey("id", func(s *store, val func(string)) { val(s.ID) }),
+		GeoIndex("location", func(s *store, loc func(Rect)) { loc(Point(s.Lat, s.Lon)) }),
+	)
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ids := func(res []*store, err error) (ids []string) {
+		assert.NoError(t, err)
+		for _, s := range res {
+			ids = append(ids, s.ID)
+		}
+
+		return
+	}
+
+	assert.Equal(t, []string{"tel-aviv", "jerusalem", "haifa"}, ids(col.Nearest("location", 32.0, 34.8, 3)))
+	assert.Equal(t, []string{"paris", "london"}, ids(col.Nearest("location", 48.0, 2.0, 2)))
+	assert.Equal(t, []string{"grid-4-5", "grid-4-6", "grid-5-5", "grid-5-6"}, sorted(ids(col.Nearest("location", 14.5, 15.5, 4))))
+
+	// nearest search agrees with a linear scan
+	for _, q := range [][2]float64{{0, 0}, {30, 30}, {15.2, 12.7}, {-20, 100}} {
+		expected := make([]*store, len(stores))
+		copy(expected, stores)
+		sort.SliceStable(expected, func(i, j int) bool {
+			return haversine(q[0], q[1], expected[i].Lat, expected[i].Lon) < haversine(q[0], q[1], expected[j].Lat, expected[j].Lon)
+		})
+
+		actual, err := col.Nearest("location", q[0], q[1], 5)
+		assert.NoError(t, err)
+		for i := range actual {
+			assert.InDelta(t,
+				haversine(q[0], q[1], expected[i].Lat, expected[i].Lon),
+				haversine(q[0], q[1], actual[i].Lat, actual[i].Lon), 1e-6)
+		}
+	}
+
+	assert.Equal(t, []string{"eilat", "haifa", "jerusalem", "tel-aviv"}, ids(col.Within("location", Rect{29, 34, 34, 36})))
+	assert.Len(t, ids(col.Within("location", Rect{10, 10, 12.5, 19})), 30)
+	assert.Empty(t, ids(col.Within("location", Rect{-10, -10, -5, -5})))
+
+	_, err = col.Within("area", Rect{})
+	assert.EqualError(t, err, `collection "stores" has no spatial index "area"`)
+	assert.True(t, math.Abs(haversine(32.0853, 34.7818, 31.7683, 35.2137)-54e3) < 2e3)
+
+	// the tree is rebuilt with the next generation
+	stores = stores[1:6]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"jerusalem"}, ids(col.Nearest("location", 32.0, 34.8, 1)))
+}
+
+func TestRectDistance(t *testing.T) {
+	// the distance to a rect is never more than the distance to any of its
+	// points
+	bound := func(r Rect, lat, lon float64) {
+		d := r.distance(lat, lon)
+		for i := 0.0; i <= 20; i++ {
+			for j := 0.0; j <= 20; j++ {
+				pLat, pLon := r.MinLat+(r.MaxLat-r.MinLat)*i/20, r.MinLon+(r.MaxLon-r.MinLon)*j/20
+				assert.LessOrEqual(t, d, haversine(lat, lon, pLat, pLon)+1e-6, "(%v, %v) to %v", lat, lon, r)
+			}
+		}
+	}
+
+	t.Run("pole", func(t *testing.T) {
+		r := Rect{80, 0, 85, 10}
+
+		// the closest point of the rect isn't at the latitude of the point
+		assert.Less(t, r.distance(80, 100), haversine(80, 100, 80, 10))
+		assert.InDelta(t, haversine(89, 5, 85, 5), r.distance(89, 5), 1e-6)
+		assert.InDelta(t, haversine(88, -175, 85, 0), r.distance(88, -175), 1e-6)
+
+		for _, q := range [][2]float64{{80, 100}, {89, 5}, {88, -175}, {70, 90}, {89.9, -90}, {90, 0}} {
+			bound(r, q[0], q[1])
+		}
+	})
+
+	t.Run("antimeridian", func(t *testing.T) {
+		r := Rect{-10, 170, 10, 180}
+
+		assert.InDelta(t, haversine(0, -179, 0, 180), r.distance(0, -179), 1e-6)
+		assert.InDelta(t, 111e3, r.distance(0, -179), 1e3)
+
+		for _, q := range [][2]float64{{0, -179}, {20, -170}, {-30, -150}, {0, 0}, {45, 90}} {
+			bound(r, q[0], q[1])
+		}
+
+		assert.Equal(t, 0.0, r.distance(5, 175))
+	})
+}
+
+func sorted(s []string) []string {
+	sort.Strings(s)
+	return s
+}
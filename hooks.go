@@ -0,0 +1,48 @@
+package inventory
+
+import (
+	"context"
+	"sync"
+)
+
+type commitHooksKey struct{}
+
+// commitHooks are the functions that are called once the reload in which they
+// were registered is committed
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func withCommitHooks(ctx context.Context) (context.Context, *commitHooks) {
+	hooks := &commitHooks{}
+
+	return context.WithValue(ctx, commitHooksKey{}, hooks), hooks
+}
+
+func (h *commitHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// AfterCommit registers fn to be called once the reload that provided ctx to
+// the ExtractFunc is committed. it lets an ExtractFunc keep state, such as the
+// version of the origin source, only if the extracted items were committed. if
+// ctx isn't of a reload, fn is called immediately
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.mu.Lock()
+	hooks.fns = append(hooks.fns, fn)
+	hooks.mu.Unlock()
+}
@@ -0,0 +1,335 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewHTTPExtractor creates an HTTPExtractor of T that fetches the items from
+// the provided url
+func NewHTTPExtractor[T any](url string, opts ...HTTPOpt) *HTTPExtractor[T] {
+	x := &HTTPExtractor[T]{
+		url: url,
+		httpOptions: httpOptions{
+			client:     http.DefaultClient,
+			header:     http.Header{},
+			itemsField: "items",
+			attempts:   3,
+			backoff:    DefaultBackoff,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&x.httpOptions)
+	}
+
+	return x
+}
+
+// HTTPExtractor extracts items of T from a JSON endpoint. the response is
+// either a JSON array of items or a JSON object that holds the items in its
+// items field. pages are followed by the "next" link of the Link header or by
+// the next cursor field of the response, if defined by HTTPCursor.
+//
+// the ETag and the Last-Modified headers of the first page are sent back on
+// the next extraction, so an unchanged response skips the reload. failed
+// requests are retried with backoff.
+//
+// its Extract method is meant to be used as the Source of a Collection:
+//
+//	x := NewHTTPExtractor[*book]("http://books.internal/books")
+//	books := NewCollection[*book](db, "books", Source(x.Extract), ...)
+type HTTPExtractor[T any] struct {
+	httpOptions
+
+	url string
+
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+}
+
+// HTTPOpt is an option for instrumenting an HTTPExtractor
+type HTTPOpt func(*httpOptions)
+
+type httpOptions struct {
+	client      *http.Client
+	header      http.Header
+	itemsField  string
+	cursorField string
+	cursorParam string
+	attempts    int
+	backoff     Backoff
+}
+
+// HTTPClient sets the client of the HTTPExtractor. the default is
+// http.DefaultClient
+func HTTPClient(client *http.Client) HTTPOpt {
+	return func(o *httpOptions) {
+		o.client = client
+	}
+}
+
+// HTTPHeader adds a header to the requests of the HTTPExtractor
+func HTTPHeader(key, value string) HTTPOpt {
+	return func(o *httpOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// HTTPItems sets the field of a JSON object response that holds the items.
+// the default is "items"
+func HTTPItems(field string) HTTPOpt {
+	return func(o *httpOptions) {
+		o.itemsField = field
+	}
+}
+
+// HTTPCursor sets the field of a JSON object response that holds the cursor
+// of the next page, which is sent in the provided query param. an empty or a
+// missing cursor marks the last page
+func HTTPCursor(field, param string) HTTPOpt {
+	return func(o *httpOptions) {
+		o.cursorField = field
+		o.cursorParam = param
+	}
+}
+
+// HTTPRetry sets the max number of attempts of every request and the backoff
+// between them. network errors, 429 and 5xx responses are retried. the
+// default is 3 attempts with DefaultBackoff
+func HTTPRetry(attempts int, backoff Backoff) HTTPOpt {
+	return func(o *httpOptions) {
+		o.attempts = max(attempts, 1)
+		o.backoff = backoff
+	}
+}
+
+// Extract fetches all the pages of the items. it returns ErrNotModified if
+// the first page hasn't changed since the last committed extraction
+func (x *HTTPExtractor[T]) Extract(ctx context.Context, load func(in ...T)) (err error) {
+	x.mu.Lock()
+	etag, lastModified := x.etag, x.lastModified
+	x.mu.Unlock()
+
+	next, err := url.Parse(x.url)
+	if err != nil {
+		return
+	}
+
+	// a cursor or link that points back to a fetched page would loop forever
+	visited := map[string]bool{}
+
+	for page := 1; next != nil; page++ {
+		if visited[next.String()] {
+			return fmt.Errorf("page %d of %s repeats %s", page, x.url, next)
+		}
+		visited[next.String()] = true
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, next.String(), nil)
+		if err != nil {
+			return
+		}
+
+		for k, v := range x.header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Accept", "application/json")
+
+		if page == 1 {
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+
+			if lastModified != "" {
+				req.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+
+		var res *http.Response
+		res, err = x.do(req)
+		if err != nil {
+			return
+		}
+
+		var (
+			items  []T
+			cursor string
+		)
+
+		items, cursor, err = x.decode(res, page)
+		if err != nil {
+			return
+		}
+
+		if page == 1 {
+			etag, lastModified = res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+		}
+
+		if len(items) > 0 {
+			load(items...)
+		}
+
+		next, err = x.nextPage(res, cursor)
+		if err != nil {
+			return
+		}
+	}
+
+	AfterCommit(ctx, func() {
+		x.mu.Lock()
+		x.etag, x.lastModified = etag, lastModified
+		x.mu.Unlock()
+	})
+
+	return
+}
+
+// do sends the request, retrying failures with backoff
+func (x *HTTPExtractor[T]) do(req *http.Request) (res *http.Response, err error) {
+	for attempt := 1; ; attempt++ {
+		res, err = x.client.Do(req.Clone(req.Context()))
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+		case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+			if seconds, convErr := strconv.Atoi(res.Header.Get("Retry-After")); convErr == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+
+			err = fmt.Errorf("unexpected status %q from %s", res.Status, req.URL)
+		default:
+			return
+		}
+
+		if attempt >= x.attempts {
+			return
+		}
+
+		if err = sleep(req.Context(), max(delay, x.backoff.Delay(attempt))); err != nil {
+			return
+		}
+	}
+}
+
+// decode decodes the items and the next cursor of the response
+func (x *HTTPExtractor[T]) decode(res *http.Response, page int) (items []T, cursor string, err error) {
+	defer res.Body.Close()
+
+	if page == 1 && res.StatusCode == http.StatusNotModified {
+		return nil, "", ErrNotModified
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %q from %s", res.Status, res.Request.URL)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("failed to decode page %d from %s: %w", page, res.Request.URL, err)
+		}
+	}()
+
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte{'['}) {
+		err = json.Unmarshal(body, &items)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return
+	}
+
+	raw, ok := fields[x.itemsField]
+	if !ok {
+		err = fmt.Errorf("missing %q field", x.itemsField)
+		return
+	}
+
+	if err = json.Unmarshal(raw, &items); err != nil {
+		return
+	}
+
+	if raw, ok = fields[x.cursorField]; x.cursorField == "" || !ok || string(raw) == "null" {
+		return
+	}
+
+	if err = json.Unmarshal(raw, &cursor); err != nil {
+		cursor, err = string(raw), nil
+	}
+
+	return
+}
+
+// nextPage returns the url of the next page by the Link header or by the
+// cursor. it returns nil for the last page
+func (x *HTTPExtractor[T]) nextPage(res *http.Response, cursor string) (next *url.URL, err error) {
+	if link := linkNext(res.Header.Values("Link")); link != "" {
+		return res.Request.URL.Parse(link)
+	}
+
+	if x.cursorParam == "" || cursor == "" {
+		return
+	}
+
+	next, err = url.Parse(x.url)
+	if err != nil {
+		return
+	}
+
+	q := next.Query()
+	q.Set(x.cursorParam, cursor)
+	next.RawQuery = q.Encode()
+
+	return
+}
+
+// linkNext returns the target of the "next" relation in the provided Link
+// headers
+func linkNext(headers []string) string {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			target, params, _ := strings.Cut(link, ";")
+
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(k, "rel") {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,185 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPExtractor(t *testing.T) {
+	var (
+		version  atomic.Int32
+		requests atomic.Int32
+		failures atomic.Int32
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/books", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		etag := fmt.Sprintf(`"v%d"`, version.Load())
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Link", `</books/2>; rel="next"`)
+		_, _ = fmt.Fprintf(w, `[{"id":"1","title":"Dune v%d"}]`, version.Load())
+	})
+	mux.HandleFunc("/books/2", func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `{"items":[{"id":"2","title":"Dune Messiah"}],"next":3}`)
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after") {
+		case "":
+			_, _ = fmt.Fprint(w, `{"data":[{"id":"1"}],"next":"a"}`)
+		case "a":
+			_, _ = fmt.Fprint(w, `{"data":[{"id":"2"}],"next":3}`)
+		case "3":
+			_, _ = fmt.Fprint(w, `{"data":[{"id":"3"}],"next":null}`)
+		}
+	})
+
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</loop?page=2>; rel="next"`)
+		if r.URL.Query().Get("page") == "2" {
+			w.Header().Set("Link", `</loop>; rel="next"`)
+		}
+
+		_, _ = fmt.Fprint(w, `[{"id":"1"}]`)
+	})
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"items":[{"id":"1"}],"next":"a"}`)
+	})
+
+	fetching, release := make(chan struct{}), make(chan struct{})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+
+		_, _ = fmt.Fprint(w, `[{"id":"1"}]`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	retry := HTTPRetry(3, Backoff{Initial: time.Millisecond})
+
+	x := NewHTTPExtractor[*book](srv.URL+"/books", retry)
+	col := NewCollection[*book](NewDB(), "books",
+		Source(x.Extract),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+	)
+	byID := col.GetBy("id")
+
+	failures.Store(2)
+	res, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, res.Items)
+	assert.False(t, res.Unchanged)
+
+	b, ok := byID("2")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune Messiah", b.Title)
+
+	res, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, res.Unchanged)
+	assert.Equal(t, 2, col.Stats().Items)
+	assert.Equal(t, int32(2), requests.Load())
+
+	b, ok = byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune v0", b.Title)
+
+	// a failed reload doesn't commit the etag of its first page
+	version.Store(1)
+	failures.Store(3)
+	_, err = col.Reload(context.Background())
+	assert.ErrorContains(t, err, `unexpected status "503 Service Unavailable"`)
+
+	res, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, res.Unchanged)
+
+	b, ok = byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune v1", b.Title)
+
+	// the db isn't locked for writes while the items are fetched
+	db := NewDB()
+	slow := NewCollection[*book](db, "slow",
+		Source(NewHTTPExtractor[*book](srv.URL+"/slow").Extract),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+	)
+
+	reloaded := make(chan error, 1)
+	go func() {
+		_, err := slow.Reload(context.Background())
+		reloaded <- err
+	}()
+
+	<-fetching
+	updated := make(chan struct{})
+	go func() {
+		_ = db.Update(func(DBWriter) error { return nil })
+		close(updated)
+	}()
+
+	select {
+	case <-updated:
+	case <-time.After(time.Second):
+		t.Error("the db is locked while fetching")
+	}
+
+	close(release)
+	assert.NoError(t, <-reloaded)
+	assert.Equal(t, 1, slow.CountAll())
+
+	var ids []string
+	err = NewHTTPExtractor[*book](srv.URL+"/cursor", HTTPItems("data"), HTTPCursor("next", "after")).
+		Extract(context.Background(), func(in ...*book) {
+			for _, b := range in {
+				ids = append(ids, b.ID)
+			}
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	err = NewHTTPExtractor[*book](srv.URL+"/cursor", retry).
+		Extract(context.Background(), func(in ...*book) {})
+	assert.ErrorContains(t, err, `missing "items" field`)
+
+	// a cursor or link that repeats a fetched page fails the extraction
+	var loaded int
+	err = NewHTTPExtractor[*book](srv.URL+"/stuck", HTTPCursor("next", "after")).
+		Extract(context.Background(), func(in ...*book) { loaded += len(in) })
+	assert.EqualError(t, err, fmt.Sprintf("page 3 of %[1]s/stuck repeats %[1]s/stuck?after=a", srv.URL))
+	assert.Equal(t, 2, loaded)
+
+	err = NewHTTPExtractor[*book](srv.URL+"/loop").
+		Extract(context.Background(), func(in ...*book) {})
+	assert.EqualError(t, err, fmt.Sprintf("page 3 of %[1]s/loop repeats %[1]s/loop", srv.URL))
+}
+
+func Test_linkNext(t *testing.T) {
+	assert.Equal(t, "/b?page=2", linkNext([]string{`</a>; rel="prev", </b?page=2>; rel="next"`}))
+	assert.Equal(t, "/c", linkNext([]string{`</a>; rel=prev`, `</c>; rel="last next"`}))
+	assert.Equal(t, "", linkNext([]string{`</a>; rel="prev"`}))
+	assert.Equal(t, "", linkNext(nil))
+}
@@ -60,3 +60,38 @@ func writeKey(dst *bytes.Buffer, kind, key, val string) {
 	dst.WriteString(val)
 	dst.WriteRune(curlyEnd)
 }
+
+// valuesTag is the tag of the tags of the values of an index of a kind, so the
+// values of a single index are iterated without scanning all the tags of the
+// db. tags that no longer have keys are left in it until the kind is reloaded
+func valuesTag(kind, index string) string {
+	return mkKey(kind, "#"+index, "")
+}
+
+// tagIndexValue tags the provided key with the tag of its value of the
+// provided index, and adds the tag to the values of the index
+func tagIndexValue(writer DBWriter, key, kind, index, val string) {
+	tag := mkKey(kind, index, val)
+
+	writer.Tag(key, tag)
+	writer.Tag(tag, valuesTag(kind, index))
+}
+
+// indexValues calls fn with the values of the provided index of a kind that
+// are shared by any keys, along with their tags and number of keys
+func indexValues(viewer DBViewer, kind, index string, fn func(val, tag string, keys int) bool) {
+	prefix := kind + string(curlyStart) + index + string(colon)
+
+	viewer.Iter(valuesTag(kind, index), func(tag string, _ func() (any, bool)) bool {
+		v, ok := indexValue(tag, prefix)
+		if !ok {
+			return true
+		}
+
+		if keys := viewer.Count(tag); keys > 0 {
+			return fn(v, tag, keys)
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,133 @@
+package inventory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoadFunc loads a single item by the provided value of the provided index
+// from the origin source. ok is false if there is no such item
+type LoadFunc[T any] func(ctx context.Context, index, val string) (item T, ok bool, err error)
+
+// ReadThrough loads the items of the collection on demand. a miss of a Getter
+// of the collection loads the item by the provided loader and puts it in the
+// db along with the tags of all of its indexes. items that are not found by
+// the loader are recorded as Absent in the db, under the tag of the kind, and
+// are not looked up again for the provided negative ttl. a zero ttl doesn't
+// record misses.
+//
+// a collection without a Source is loaded only on demand, so its reloads
+// clear the items that were loaded so far instead of loading all the items,
+// and its keys are reloaded by the loader unless it has a KeyedSource. queries
+// of the collection see only the items that were loaded so far
+func ReadThrough[T any](loader LoadFunc[T], negativeTTL time.Duration) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.loader = loader
+		c.negativeTTL = negativeTTL
+	}
+}
+
+// lazyState is the state of the read-through loads of a collection
+type lazyState struct {
+	mu       sync.Mutex
+	inflight map[string]*inflightLoad
+}
+
+// inflightLoad is a load that concurrent misses of the same key wait for
+type inflightLoad struct {
+	done chan struct{}
+}
+
+// readThrough loads the item of the provided index value by the loader of the
+// collection and gets it from the db. items that are not found are recorded
+// as absent for the negative ttl of the collection
+func (c *Collection[T]) readThrough(ctx context.Context, index string, primary bool, val string) (t T, presence Presence) {
+	key := mkKey(c.kind, index, val)
+
+	c.lazy.mu.Lock()
+	if load, loading := c.lazy.inflight[key]; loading {
+		c.lazy.mu.Unlock()
+		<-load.done
+
+		return c.find(c.db, index, primary, val)
+	}
+
+	if c.lazy.inflight == nil {
+		c.lazy.inflight = map[string]*inflightLoad{}
+	}
+
+	load := &inflightLoad{done: make(chan struct{})}
+	c.lazy.inflight[key] = load
+	c.lazy.mu.Unlock()
+
+	defer func() {
+		c.lazy.mu.Lock()
+		delete(c.lazy.inflight, key)
+		c.lazy.mu.Unlock()
+
+		close(load.done)
+	}()
+
+	item, found, err := c.loader(ctx, index, val)
+	if err != nil {
+		c.log().Error("failed to load item", "kind", c.kind, "index", index, "value", val, "error", err)
+		return
+	}
+
+	if found {
+		if err = c.put(item); err != nil {
+			c.log().Error("failed to put loaded item", "kind", c.kind, "index", index, "value", val, "error", err)
+			return
+		}
+
+		c.mu.Lock()
+		c.stats.Loads++
+		c.mu.Unlock()
+
+		if t, presence = c.find(c.db, index, primary, val); presence == Present {
+			return
+		}
+	}
+
+	if c.negativeTTL > 0 {
+		c.markAbsent(index, val, time.Now().Add(c.negativeTTL))
+	}
+
+	return t, Absent
+}
+
+// put puts the provided item in the db, replacing the item of its primary key
+// and the absence of its index values
+func (c *Collection[T]) put(item T) (err error) {
+	var (
+		keys   []string
+		staged []stagedItem[T]
+	)
+
+	c.indexer([]T{item}, func(key string, item T) {
+		keys = append(keys, key)
+		staged = append(staged, stagedItem[T]{key, item})
+	})
+
+	_, _, err = c.replace(append(keys, c.absentKeys(item)...), staged)
+
+	return
+}
+
+// extractKeysByLoader is the KeyedExtractFunc of a read-through collection
+// without a KeyedSource
+func (c *Collection[T]) extractKeysByLoader(ctx context.Context, keys []string, load func(in ...T)) error {
+	for _, key := range keys {
+		item, ok, err := c.loader(ctx, c.pk.key, key)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			load(item)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadThrough(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		books = map[string]*book{
+			"1": {"1", "Dune", "Frank Herbert"},
+			"2": {"2", "Dune Messiah", "Frank Herbert"},
+		}
+		calls   atomic.Int32
+		release chan struct{}
+	)
+
+	col := NewCollection[*book](NewDB(), "books",
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		AdditionalKey("title", func(b *book, val func(string)) { val(b.Title) }),
+		ReadThrough(func(ctx context.Context, index, val string) (*book, bool, error) {
+			calls.Add(1)
+			if release != nil {
+				<-release
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, b := range books {
+				if (index == "id" && b.ID == val) || (index == "title" && b.Title == val) {
+					return b, true, nil
+				}
+			}
+
+			return nil, false, nil
+		}, 20*time.Millisecond),
+	)
+	byID := col.GetBy("id")
+	byTitle := col.GetBy("title")
+
+	b, ok := byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune", b.Title)
+
+	_, ok = byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// loaded by a secondary index, then served by all of them
+	b, ok = byTitle("Dune Messiah")
+	assert.True(t, ok)
+	assert.Equal(t, "2", b.ID)
+
+	_, ok = byID("2")
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, 2, col.Stats().Loads)
+
+	// misses are cached for the negative ttl
+	_, ok = byID("3")
+	assert.False(t, ok)
+	_, ok = byID("3")
+	assert.False(t, ok)
+	assert.Equal(t, int32(3), calls.Load())
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = byID("3")
+	assert.False(t, ok)
+	assert.Equal(t, int32(4), calls.Load())
+
+	// reloading the keys clears their misses
+	mu.Lock()
+	books["3"] = &book{"3", "Children of Dune", "Frank Herbert"}
+	books["1"] = &book{"1", "Dune (1965)", "Frank Herbert"}
+	mu.Unlock()
+
+	res, err := col.ReloadKeys(context.Background(), "1", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Items)
+
+	b, ok = byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune (1965)", b.Title)
+
+	_, ok = byTitle("Dune")
+	assert.False(t, ok)
+	assert.Equal(t, int32(7), calls.Load())
+
+	_, ok = byID("3")
+	assert.True(t, ok)
+	assert.Equal(t, int32(7), calls.Load())
+
+	// reloading clears only what was loaded
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, col.count())
+
+	// concurrent misses of the same key are loaded once
+	calls.Store(0)
+	release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, ok := byID("2")
+			assert.True(t, ok)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 1, col.count())
+}
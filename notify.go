@@ -0,0 +1,228 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Notification is a message that was sent on a channel of a Notifier
+type Notification struct {
+	Channel string `json:"channel"`
+	Payload string `json:"payload"`
+}
+
+// Notifier streams notifications, such as the ones that are sent by triggers
+// in the db that holds the origin source
+type Notifier interface {
+	// Listen calls fn with every notification of the provided channels until
+	// ctx is done
+	Listen(ctx context.Context, channels []string, fn func(Notification)) error
+}
+
+// KeysFunc extracts the primary key values of the changed items from the
+// payload of a notification. no keys means that all the items might have
+// changed
+type KeysFunc func(payload string) []string
+
+// PayloadKey is a KeysFunc of payloads that are a single primary key value
+func PayloadKey(payload string) []string {
+	if payload == "" {
+		return nil
+	}
+
+	return []string{payload}
+}
+
+// NewTriggers creates Triggers over the provided notifier
+func NewTriggers(notifier Notifier, opts ...TriggersOpt) *Triggers {
+	t := &Triggers{
+		notifier: notifier,
+		logger:   slog.Default(),
+		routes:   map[string][]trigger{},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Triggers reloads collections by notifications. notifications that are sent
+// while Run isn't listening are lost, so running it again should be followed
+// by a full reload of the collections
+type Triggers struct {
+	notifier Notifier
+	logger   *slog.Logger
+
+	mu     sync.RWMutex
+	routes map[string][]trigger
+}
+
+type trigger struct {
+	col  Reloadable
+	keys KeysFunc
+}
+
+// TriggersOpt is an option for instrumenting Triggers
+type TriggersOpt func(*Triggers)
+
+// TriggersLogger sets the logger Triggers report failed reloads to. by
+// default, slog.Default() is used
+func TriggersLogger(logger *slog.Logger) TriggersOpt {
+	return func(t *Triggers) {
+		t.logger = logger
+	}
+}
+
+// On reloads the provided collection by the notifications of the provided
+// channel. the items of the keys that are extracted from the payload by the
+// provided KeysFunc are reloaded, or all the items if keys is nil or no keys
+// are extracted. channels should be registered before Run is called
+func (t *Triggers) On(channel string, col Reloadable, keys KeysFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.routes[channel] = append(t.routes[channel], trigger{col, keys})
+}
+
+// Run listens to the registered channels until ctx is done
+func (t *Triggers) Run(ctx context.Context) error {
+	t.mu.RLock()
+	channels := make([]string, 0, len(t.routes))
+	for channel := range t.routes {
+		channels = append(channels, channel)
+	}
+	t.mu.RUnlock()
+
+	sort.Strings(channels)
+
+	return t.notifier.Listen(ctx, channels, func(n Notification) {
+		t.mu.RLock()
+		routes := t.routes[n.Channel]
+		t.mu.RUnlock()
+
+		for _, r := range routes {
+			var keys []string
+			if r.keys != nil {
+				keys = r.keys(n.Payload)
+			}
+
+			var err error
+			if len(keys) == 0 {
+				_, err = r.col.Reload(ctx)
+			} else {
+				_, err = r.col.ReloadKeys(ctx, keys...)
+			}
+
+			if err != nil {
+				t.logger.Error("failed to reload on notification", "kind", r.col.Kind(), "channel", n.Channel, "error", err)
+			}
+		}
+	})
+}
+
+// NotifyConn is implemented by connections of database/sql drivers that
+// support notifications
+type NotifyConn interface {
+	// Listen subscribes the connection to the provided channel
+	Listen(ctx context.Context, channel string) error
+
+	// WaitForNotification blocks until a notification of a subscribed channel
+	// is received or ctx is done
+	WaitForNotification(ctx context.Context) (Notification, error)
+}
+
+// NotifyAdapter adapts a connection of a database/sql driver, as provided by
+// sql.Conn.Raw, to NotifyConn
+type NotifyAdapter func(driverConn any) (NotifyConn, error)
+
+// NewSQLNotifier creates an SQLNotifier over the provided db. database/sql
+// has no notifications API, so the connections of the driver are adapted to
+// NotifyConn by the provided adapter. with pgx, for example:
+//
+//	NewSQLNotifier(db, func(driverConn any) (NotifyConn, error) {
+//		conn, ok := driverConn.(*stdlib.Conn)
+//		if !ok {
+//			return nil, fmt.Errorf("unexpected connection %T", driverConn)
+//		}
+//
+//		// pgxNotifyConn sends "listen" by conn.Conn().Exec, and waits by
+//		// conn.Conn().WaitForNotification
+//		return &pgxNotifyConn{conn.Conn()}, nil
+//	})
+func NewSQLNotifier(db *sql.DB, adapt NotifyAdapter) *SQLNotifier {
+	return &SQLNotifier{db: db, adapt: adapt}
+}
+
+// SQLNotifier is a Notifier over a database/sql db. it dedicates a connection
+// of the db to listening, which is used through its NotifyAdapter
+type SQLNotifier struct {
+	db    *sql.DB
+	adapt NotifyAdapter
+}
+
+// Listen calls fn with every notification of the provided channels until ctx
+// is done
+func (n *SQLNotifier) Listen(ctx context.Context, channels []string, fn func(Notification)) error {
+	conn, err := n.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		nc, err := n.adapt(driverConn)
+		if err != nil {
+			return err
+		}
+
+		for _, channel := range channels {
+			if err = nc.Listen(ctx, channel); err != nil {
+				return fmt.Errorf("failed to listen to %q: %w", channel, err)
+			}
+		}
+
+		for {
+			notification, err := nc.WaitForNotification(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fn(notification)
+		}
+	})
+}
+
+// NewMemoryNotifier creates a MemoryNotifier
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// MemoryNotifier is a Notifier in memory, for tests
+type MemoryNotifier struct {
+	fanout fanout[Notification]
+}
+
+// Notify sends the provided payload on the provided channel to all the
+// listeners of the channel
+func (m *MemoryNotifier) Notify(ctx context.Context, channel, payload string) error {
+	return m.fanout.publish(ctx, Notification{channel, payload})
+}
+
+// Listen calls fn with every notification of the provided channels until ctx
+// is done
+func (m *MemoryNotifier) Listen(ctx context.Context, channels []string, fn func(Notification)) error {
+	return m.fanout.subscribe(ctx, func(n Notification) bool {
+		return slices.Contains(channels, n.Channel)
+	}, fn)
+}
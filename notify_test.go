@@ -0,0 +1,198 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotifyDriver is a driver whose connections receive the notifications
+// of its notifier
+type fakeNotifyDriver struct {
+	fakeSQLDriver
+
+	notifier *MemoryNotifier
+}
+
+func (d *fakeNotifyDriver) Open(string) (driver.Conn, error) {
+	return &fakeNotifyConn{
+		fakeNotifyDriver: d,
+		ch:               make(chan Notification, 64),
+		stopped:          make(chan struct{}),
+	}, nil
+}
+
+func fakeNotifyAdapter(driverConn any) (NotifyConn, error) {
+	conn, ok := driverConn.(*fakeNotifyConn)
+	if !ok {
+		return nil, fmt.Errorf("driver connection %T doesn't support notifications", driverConn)
+	}
+
+	return conn, nil
+}
+
+type fakeNotifyConn struct {
+	*fakeNotifyDriver
+
+	channels []string
+	once     sync.Once
+	ch       chan Notification
+	stopped  chan struct{}
+}
+
+func (c *fakeNotifyConn) Listen(ctx context.Context, channel string) error {
+	c.channels = append(c.channels, channel)
+	return nil
+}
+
+func (c *fakeNotifyConn) WaitForNotification(ctx context.Context) (Notification, error) {
+	c.once.Do(func() {
+		go func() {
+			defer close(c.stopped)
+			_ = c.notifier.Listen(ctx, c.channels, func(n Notification) { c.ch <- n })
+		}()
+	})
+
+	select {
+	case <-ctx.Done():
+		<-c.stopped
+		return Notification{}, ctx.Err()
+	case n := <-c.ch:
+		return n, nil
+	}
+}
+
+func listening(m *MemoryNotifier, channel string) func() bool {
+	return func() bool {
+		return m.fanout.accepted(Notification{Channel: channel}) > 0
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	notifier := NewMemoryNotifier()
+
+	fake := &fakeNotifyDriver{notifier: notifier}
+	driverName := fmt.Sprintf("inventory-fake-%p", fake)
+	sql.Register(driverName, fake)
+
+	sqlDB, err := sql.Open(driverName, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sqlDB.Close()
+
+	var (
+		mu      sync.Mutex
+		books   = map[string]*book{"1": {"1", "Dune", "Frank Herbert"}}
+		reloads int
+	)
+
+	col := NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			reloads++
+			for _, b := range books {
+				load(b)
+			}
+
+			return nil
+		}),
+		KeyedSource(func(ctx context.Context, keys []string, load func(in ...*book)) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, key := range keys {
+				if b, ok := books[key]; ok {
+					load(b)
+				}
+			}
+
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+	)
+	byID := col.GetBy("id")
+
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	update := func(b *book) {
+		mu.Lock()
+		books[b.ID] = b
+		mu.Unlock()
+	}
+
+	titled := func(id, title string) func() bool {
+		return func() bool {
+			b, ok := byID(id)
+			return ok && b.Title == title
+		}
+	}
+
+	for name, n := range map[string]Notifier{
+		"memory": notifier,
+		"sql":    NewSQLNotifier(sqlDB, fakeNotifyAdapter),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mu.Lock()
+			books = map[string]*book{"1": {"1", "Dune", "Frank Herbert"}}
+			reloads = 0
+			mu.Unlock()
+
+			_, err := col.Reload(context.Background())
+			assert.NoError(t, err)
+
+			triggers := NewTriggers(n, TriggersLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			triggers.On("books_changed", col, PayloadKey)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- triggers.Run(ctx) }()
+
+			defer func() {
+				cancel()
+				assert.NoError(t, <-done)
+			}()
+
+			assert.Eventually(t, listening(notifier, "books_changed"), time.Second, time.Millisecond)
+
+			update(&book{"1", "Dune (1965)", "Frank Herbert"})
+			assert.NoError(t, notifier.Notify(context.Background(), "books_changed", "1"))
+			assert.Eventually(t, titled("1", "Dune (1965)"), time.Second, time.Millisecond)
+
+			update(&book{"2", "Dune Messiah (1969)", "Frank Herbert"})
+			assert.NoError(t, notifier.Notify(context.Background(), "authors_changed", ""))
+			assert.NoError(t, notifier.Notify(context.Background(), "books_changed", ""))
+			assert.Eventually(t, titled("2", "Dune Messiah (1969)"), time.Second, time.Millisecond)
+
+			mu.Lock()
+			assert.Equal(t, 2, reloads)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestSQLNotifierUnsupported(t *testing.T) {
+	fake := &fakeSQLDriver{}
+	driverName := fmt.Sprintf("inventory-fake-%p", fake)
+	sql.Register(driverName, fake)
+
+	sqlDB, err := sql.Open(driverName, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sqlDB.Close()
+
+	err = NewSQLNotifier(sqlDB, fakeNotifyAdapter).Listen(context.Background(), []string{"books_changed"}, func(Notification) {})
+	assert.EqualError(t, err, "driver connection *inventory.fakeSQLDriver doesn't support notifications")
+}
@@ -0,0 +1,153 @@
+package inventory
+
+import (
+	"context"
+	"time"
+)
+
+// Presence is the state of an item in a Collection
+type Presence int
+
+const (
+	// NotLoaded means that the item isn't in the collection and it isn't
+	// known whether it exists in the origin source
+	NotLoaded Presence = iota
+
+	// Present means that the item is in the collection
+	Present
+
+	// Absent means that the item is known to be absent from the origin source
+	Absent
+)
+
+func (p Presence) String() string {
+	switch p {
+	case Present:
+		return "present"
+	case Absent:
+		return "absent"
+	default:
+		return "not loaded"
+	}
+}
+
+// Lookup is a Getter that reports the presence of the item
+type Lookup[T any] func(val string) (T, Presence)
+
+// absence is the value of a negative entry in the db, which records that
+// an index value is absent from the origin source until it expires
+type absence struct {
+	expires time.Time
+}
+
+// absentKey is the key of the negative entry of the provided index value. it
+// never collides with the keys of the items, which are keyed by index names
+func absentKey(kind, index, val string) string {
+	return mkKey(kind, "!"+index, val)
+}
+
+// LookupBy creates a Lookup from existing index. a miss is Absent if the
+// collection is fully loaded from its Source, or if ReadThrough didn't find
+// the item, and NotLoaded otherwise
+func (c *Collection[T]) LookupBy(key string) Lookup[T] {
+	primary := key == c.pk.key
+
+	return func(val string) (t T, presence Presence) {
+		t, presence = c.find(c.db, key, primary, val)
+		if presence != NotLoaded {
+			return
+		}
+
+		c.dropExpired(key, val)
+
+		if c.loader != nil {
+			return c.readThrough(context.Background(), key, primary, val)
+		}
+
+		c.mu.Lock()
+		loaded := c.extract != nil && c.stats.Generation > 0
+		c.mu.Unlock()
+
+		if loaded {
+			presence = Absent
+		}
+
+		return
+	}
+}
+
+// find gets the item of the provided index value from the provided viewer
+// along with its presence as recorded in the db
+func (c *Collection[T]) find(viewer DBViewer, index string, primary bool, val string) (t T, presence Presence) {
+	if t, ok := c.get(viewer, index, primary, val); ok {
+		return t, Present
+	}
+
+	if i, ok := viewer.Get(absentKey(c.kind, index, val)); ok {
+		if a, ok := i.(absence); ok && time.Now().Before(a.expires) {
+			return t, Absent
+		}
+	}
+
+	return t, NotLoaded
+}
+
+// markAbsent records the provided index value as absent from the origin source
+// until the provided expiry. the entry is tagged with the kind, so reloads
+// clear it, but not with the items of the kind, so it doesn't change the
+// version of the collection
+func (c *Collection[T]) markAbsent(index, val string, expires time.Time) {
+	key := absentKey(c.kind, index, val)
+
+	_ = c.db.Update(func(writer DBWriter) error {
+		writer.Put(key, absence{expires})
+		writer.Tag(key, c.kind)
+
+		return nil
+	})
+}
+
+// dropExpired deletes the negative entry of the provided index value if it has
+// expired, so the entries of misses that aren't repeated don't pile up
+func (c *Collection[T]) dropExpired(index, val string) {
+	key := absentKey(c.kind, index, val)
+
+	expired := func(get func(key string) (any, bool)) bool {
+		i, ok := get(key)
+		if !ok {
+			return false
+		}
+
+		a, ok := i.(absence)
+
+		return ok && !time.Now().Before(a.expires)
+	}
+
+	if !expired(c.db.Get) {
+		return
+	}
+
+	_ = c.db.Update(func(writer DBWriter) error {
+		if expired(writer.Get) {
+			writer.Delete(key)
+		}
+
+		return nil
+	})
+}
+
+// absentKeys returns the keys of the negative entries of all the index values
+// of the provided item
+func (c *Collection[T]) absentKeys(item T) (keys []string) {
+	for _, idx := range append([]index[T]{c.pk}, c.keys...) {
+		if idx.ref == nil {
+			continue
+		}
+
+		idx.ref(item, func(v string) {
+			keys = append(keys, absentKey(idx.kind, idx.key, v))
+		})
+	}
+
+	return
+}
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupBy(t *testing.T) {
+	t.Run("read through", func(t *testing.T) {
+		var (
+			calls int
+			down  bool
+			books = map[string]*book{"1": {"1", "Dune", "Frank Herbert"}}
+		)
+
+		db := NewDB()
+		col := NewCollection[*book](db, "books",
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			ReadThrough(func(ctx context.Context, index, val string) (*book, bool, error) {
+				calls++
+				if down {
+					return nil, false, errors.New("connection refused")
+				}
+
+				b, ok := books[val]
+				return b, ok, nil
+			}, 20*time.Millisecond),
+			Logger[*book](slog.New(slog.NewTextHandler(io.Discard, nil))),
+		)
+		lookup := col.LookupBy("id")
+
+		b, presence := lookup("1")
+		assert.Equal(t, Present, presence)
+		assert.Equal(t, "Dune", b.Title)
+
+		// a miss doesn't change the version of the collection
+		version := col.Version()
+
+		_, presence = lookup("2")
+		assert.Equal(t, Absent, presence)
+
+		_, presence = lookup("2")
+		assert.Equal(t, Absent, presence)
+		assert.Equal(t, 2, calls)
+		assert.False(t, col.Changed(version))
+
+		_, ok := col.GetBy("id")("2")
+		assert.False(t, ok)
+		assert.Equal(t, 2, calls)
+
+		// the absence is stored under the tag of the kind
+		var keys []string
+		db.Iter("books", func(key string, _ func() (any, bool)) bool {
+			keys = append(keys, key)
+			return true
+		})
+		assert.ElementsMatch(t, []string{"books{id:1}", "books{!id:2}"}, keys)
+		assert.Equal(t, 1, col.count())
+
+		// reloading the key forgets its absence
+		books["2"] = &book{"2", "Dune Messiah", "Frank Herbert"}
+		_, err := col.ReloadKeys(context.Background(), "2")
+		assert.NoError(t, err)
+
+		_, presence = lookup("2")
+		assert.Equal(t, Present, presence)
+
+		// an expired absence is looked up again
+		_, presence = lookup("3")
+		assert.Equal(t, Absent, presence)
+		assert.Equal(t, 4, calls)
+
+		time.Sleep(20 * time.Millisecond)
+		_, presence = lookup("3")
+		assert.Equal(t, Absent, presence)
+		assert.Equal(t, 5, calls)
+
+		// reloading clears the absences
+		_, err = col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		_, ok = db.Get("books{!id:3}")
+		assert.False(t, ok)
+
+		// an expired absence is deleted by the lookup that finds it, even if
+		// the item can't be loaded
+		col.markAbsent("id", "3", time.Now())
+
+		down = true
+		_, presence = lookup("3")
+		assert.Equal(t, NotLoaded, presence)
+		assert.Equal(t, "not loaded", presence.String())
+
+		_, ok = db.Get("books{!id:3}")
+		assert.False(t, ok)
+	})
+
+	t.Run("fully loaded", func(t *testing.T) {
+		col := NewCollection[*book](NewDB(), "books",
+			Source(func(ctx context.Context, load func(in ...*book)) error {
+				load(&book{"1", "Dune", "Frank Herbert"})
+				return nil
+			}),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		)
+		lookup := col.LookupBy("id")
+
+		_, presence := lookup("1")
+		assert.Equal(t, NotLoaded, presence)
+
+		_, err := col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		_, presence = lookup("1")
+		assert.Equal(t, Present, presence)
+
+		_, presence = lookup("2")
+		assert.Equal(t, Absent, presence)
+	})
+}
@@ -0,0 +1,134 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Projection is a view of a Collection whose items are projected from the
+// items of the collection. unlike a Derivative, the projected items are
+// materialized along with the items of the collection, are invalidated with
+// them and are fetched by the same indexes
+type Projection[In, Out any] struct {
+	base *Collection[In]
+	kind string
+}
+
+// Project creates a Projection of the provided collection by the provided
+// func, that is stored under the provided name in relation to the items of
+// the collection. it must be created before the collection is loaded
+func Project[In, Out any](base *Collection[In], name string, fn func(in In) Out) *Projection[In, Out] {
+	p := &Projection[In, Out]{base: base, kind: fmt.Sprintf("%s/%s", base.kind, name)}
+
+	base.inferences = append(base.inferences, func(writer DBWriter, in In) {
+		if base.pk.ref == nil {
+			return
+		}
+
+		base.pk.ref(in, func(v string) {
+			itemKey := mkKey(base.kind, base.pk.key, v)
+			key := p.key(itemKey)
+
+			writer.Put(key, fn(in))
+			writer.Tag(key, p.kind, itemKey)
+		})
+	})
+
+	return p
+}
+
+// key returns the key of the projected item of the provided item key
+func (p *Projection[In, Out]) key(itemKey string) string {
+	return p.kind + strings.TrimPrefix(itemKey, p.base.kind)
+}
+
+// GetBy creates a Getter of projected items by an existing index of the
+// collection
+func (p *Projection[In, Out]) GetBy(index string) Getter[Out] {
+	primary := index == p.base.pk.key
+
+	return func(val string) (out Out, ok bool) {
+		found := func() (presence Presence) {
+			_ = p.base.db.View(func(viewer DBViewer) error {
+				if _, presence = p.base.find(viewer, index, primary, val); presence == Present {
+					out, ok = p.get(viewer, index, primary, val)
+				}
+
+				return nil
+			})
+
+			return
+		}
+
+		if found() == NotLoaded && p.base.loader != nil {
+			if _, presence := p.base.readThrough(context.Background(), index, primary, val); presence == Present {
+				found()
+			}
+		}
+
+		return
+	}
+}
+
+// get gets the projected item of the item of the provided index value
+func (p *Projection[In, Out]) get(viewer DBViewer, index string, primary bool, val string) (out Out, ok bool) {
+	itemKey := mkKey(p.base.kind, index, val)
+	if !primary {
+		viewer.Iter(itemKey, func(key string, _ func() (any, bool)) bool {
+			itemKey, ok = key, true
+			return false
+		})
+
+		if !ok {
+			return
+		}
+	}
+
+	i, ok := viewer.Get(p.key(itemKey))
+	if !ok {
+		return
+	}
+
+	out, ok = i.(Out)
+
+	return
+}
+
+// QueryBy creates a Query of projected items by an existing index of the
+// collection
+func (p *Projection[In, Out]) QueryBy(index string) Query[Out] {
+	return func(val string, filters ...func(Out) bool) (res []Out, err error) {
+		err = p.base.db.View(func(viewer DBViewer) error {
+			viewer.Iter(mkKey(p.base.kind, index, val), func(itemKey string, _ func() (any, bool)) bool {
+				key := p.key(itemKey)
+
+				i, ok := viewer.Get(key)
+				if !ok {
+					err = fmt.Errorf("failed to retrieve value of %q", key)
+					return false
+				}
+
+				out, ok := i.(Out)
+				if !ok {
+					err = fmt.Errorf("expected type %T for %q. got %T", out, key, i)
+					return false
+				}
+
+				for _, f := range filters {
+					if !f(out) {
+						return true
+					}
+				}
+
+				res = append(res, out)
+
+				return true
+			})
+
+			return err
+		})
+
+		return
+	}
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type publicBook struct {
+	Title, Author string
+}
+
+func TestProject(t *testing.T) {
+	t.Run("materialized", func(t *testing.T) {
+		var projected int
+		books := []*book{
+			{"1", "Dune", "Frank Herbert"},
+			{"2", "Dune Messiah", "Frank Herbert"},
+		}
+
+		col := NewCollection[*book](NewDB(), "books",
+			Source(func(ctx context.Context, load func(in ...*book)) error {
+				load(books...)
+				return nil
+			}),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			AdditionalKey("title", func(b *book, val func(string)) { val(b.Title) }),
+		)
+		col.MapBy("author", func(b *book, val func(string)) { val(b.Author) })
+
+		public := Project(col, "public", func(b *book) *publicBook {
+			projected++
+			return &publicBook{b.Title, b.Author}
+		})
+		byID, byTitle, byAuthor := public.GetBy("id"), public.GetBy("title"), public.QueryBy("author")
+
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 2, projected)
+
+		pb, ok := byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, &publicBook{"Dune", "Frank Herbert"}, pb)
+
+		pb, ok = byTitle("Dune Messiah")
+		assert.True(t, ok)
+		assert.Equal(t, "Dune Messiah", pb.Title)
+
+		_, ok = byID("3")
+		assert.False(t, ok)
+
+		res, err := byAuthor("Frank Herbert", func(pb *publicBook) bool { return pb.Title != "Dune" })
+		assert.NoError(t, err)
+		assert.Equal(t, []*publicBook{{"Dune Messiah", "Frank Herbert"}}, res)
+		assert.Equal(t, 2, projected)
+
+		// projected items don't count as items of the collection
+		assert.Equal(t, 2, col.CountAll())
+
+		var kinds []string
+		col.db.Iter(col.kind, func(key string, getVal func() (any, bool)) bool {
+			val, _ := getVal()
+			kinds = append(kinds, fmt.Sprintf("%T", val))
+			return true
+		})
+		assert.Equal(t, []string{"*inventory.book", "*inventory.book"}, kinds)
+
+		// projected items are invalidated along with their items
+		books = []*book{{"1", "Dune", "Brian Herbert"}}
+		_, err = col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		pb, ok = byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, "Brian Herbert", pb.Author)
+
+		_, ok = byID("2")
+		assert.False(t, ok)
+	})
+
+	t.Run("read through", func(t *testing.T) {
+		col := NewCollection[*book](NewDB(), "books",
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			ReadThrough(func(ctx context.Context, index, val string) (*book, bool, error) {
+				if val != "1" {
+					return nil, false, nil
+				}
+
+				return &book{"1", "Dune", "Frank Herbert"}, true, nil
+			}, time.Minute),
+		)
+		byID := Project(col, "public", func(b *book) publicBook { return publicBook{b.Title, b.Author} }).GetBy("id")
+
+		pb, ok := byID("1")
+		assert.True(t, ok)
+		assert.Equal(t, publicBook{"Dune", "Frank Herbert"}, pb)
+
+		_, ok = byID("2")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,491 @@
+package inventory
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Match matches values of an index
+type Match struct {
+	op   string
+	vals []string
+}
+
+// Eq matches the provided value
+func Eq(val string) Match {
+	return Match{"eq", []string{val}}
+}
+
+// In matches any of the provided values
+func In(vals ...string) Match {
+	return Match{"in", vals}
+}
+
+// Between matches the values between the provided ones, inclusive, in
+// lexical order
+func Between(from, to string) Match {
+	return Match{"between", []string{from, to}}
+}
+
+func (m Match) String() string {
+	switch m.op {
+	case "between":
+		return fmt.Sprintf("between %q and %q", m.vals[0], m.vals[1])
+	case "eq":
+		return fmt.Sprintf("= %q", m.vals[0])
+	default:
+		return fmt.Sprintf("in %q", m.vals)
+	}
+}
+
+// Predicate selects items of a collection by its indexes
+type Predicate struct {
+	op       string
+	index    string
+	match    Match
+	children []Predicate
+}
+
+// Where selects the items whose values of the provided index match
+func Where(index string, match Match) Predicate {
+	return Predicate{op: "where", index: index, match: match}
+}
+
+// And selects the items that are selected by all the provided predicates
+func And(preds ...Predicate) Predicate {
+	return Predicate{op: "and", children: preds}
+}
+
+// Or selects the items that are selected by any of the provided predicates
+func Or(preds ...Predicate) Predicate {
+	return Predicate{op: "or", children: preds}
+}
+
+// Not selects the items that are not selected by the provided predicate
+func Not(pred Predicate) Predicate {
+	return Predicate{op: "not", children: []Predicate{pred}}
+}
+
+// Selection is a query of a Collection by a Predicate. it is planned over the
+// tags of the indexes, so only the selected items are fetched from the db
+type Selection[T any] struct {
+	c       *Collection[T]
+	pred    Predicate
+	filters []func(T) bool
+	orderBy string
+	desc    bool
+	limit   int
+}
+
+// Select creates a Selection of the items of the collection by the provided
+// predicate. the zero Predicate selects all the items
+func (c *Collection[T]) Select(pred Predicate) Selection[T] {
+	return Selection[T]{c: c, pred: pred}
+}
+
+// Filter filters the selected items by the provided filters, after they are
+// fetched
+func (s Selection[T]) Filter(filters ...func(T) bool) Selection[T] {
+	s.filters = append(slices.Clip(s.filters), filters...)
+	return s
+}
+
+// OrderBy orders the selected items by their values of the provided index, in
+// lexical order. by default, items are ordered by their keys
+func (s Selection[T]) OrderBy(index string, desc bool) Selection[T] {
+	s.orderBy = index
+	s.desc = desc
+	return s
+}
+
+// Limit limits the number of selected items
+func (s Selection[T]) Limit(n int) Selection[T] {
+	s.limit = n
+	return s
+}
+
+// All returns the selected items
+func (s Selection[T]) All() (res []T, err error) {
+	var order *index[T]
+	if s.orderBy != "" {
+		idx, ok := s.c.orderIndex(s.orderBy)
+		if !ok {
+			return nil, fmt.Errorf("collection %q has no index %q", s.c.kind, s.orderBy)
+		}
+
+		order = &idx
+	}
+
+	err = s.c.db.View(func(viewer DBViewer) error {
+		p, err := s.c.plan(viewer, s.pred)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(p.keys))
+		for key := range p.keys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			i, ok := viewer.Get(key)
+			if !ok {
+				continue
+			}
+
+			t, ok := i.(T)
+			if !ok {
+				return fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+			}
+
+			if !slices.ContainsFunc(s.filters, func(f func(T) bool) bool { return !f(t) }) {
+				res = append(res, t)
+			}
+
+			if order == nil && s.limit > 0 && len(res) == s.limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if order != nil {
+		value := func(t T) (v string) {
+			order.ref(t, func(val string) {
+				if v == "" {
+					v = val
+				}
+			})
+
+			return
+		}
+
+		sort.SliceStable(res, func(i, j int) bool {
+			if s.desc {
+				return value(res[i]) > value(res[j])
+			}
+
+			return value(res[i]) < value(res[j])
+		})
+
+		if s.limit > 0 && len(res) > s.limit {
+			res = res[:s.limit]
+		}
+	}
+
+	return
+}
+
+// Count returns the number of selected items. unless the selection is
+// filtered, the items aren't fetched
+func (s Selection[T]) Count() (n int, err error) {
+	if len(s.filters) > 0 {
+		res, err := s.All()
+		return len(res), err
+	}
+
+	err = s.c.db.View(func(viewer DBViewer) error {
+		p, err := s.c.plan(viewer, s.pred)
+		if err != nil {
+			return err
+		}
+
+		n = len(p.keys)
+		if s.limit > 0 {
+			n = min(n, s.limit)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// Explain describes the plan of the selection: the indexes that are used by
+// every predicate and the number of keys it selects
+func (s Selection[T]) Explain() (res string, err error) {
+	err = s.c.db.View(func(viewer DBViewer) error {
+		p, err := s.c.plan(viewer, s.pred)
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		p.write(&b, 0)
+
+		if len(s.filters) > 0 {
+			fmt.Fprintf(&b, "filter by %d funcs\n", len(s.filters))
+		}
+
+		if s.orderBy != "" {
+			direction := "asc"
+			if s.desc {
+				direction = "desc"
+			}
+
+			fmt.Fprintf(&b, "order by %s %s\n", s.orderBy, direction)
+		}
+
+		if s.limit > 0 {
+			fmt.Fprintf(&b, "limit %d\n", s.limit)
+		}
+
+		res = b.String()
+
+		return nil
+	})
+
+	return
+}
+
+// queryPlan is the evaluated plan of a predicate
+type queryPlan struct {
+	desc     string
+	keys     map[string]struct{}
+	bits     *bitmap
+	children []*queryPlan
+}
+
+func (p *queryPlan) write(b *strings.Builder, depth int) {
+	n := len(p.keys)
+	if p.keys == nil && p.bits != nil {
+		n = p.bits.cardinality()
+	}
+
+	fmt.Fprintf(b, "%s%s (%d keys)\n", strings.Repeat("  ", depth), p.desc, n)
+
+	for _, child := range p.children {
+		child.write(b, depth+1)
+	}
+}
+
+func (c *Collection[T]) indexByName(name string) (index[T], bool) {
+	if c.pk.ref != nil && c.pk.key == name {
+		return c.pk, true
+	}
+
+	for _, idx := range c.keys {
+		if idx.key == name {
+			return idx, true
+		}
+	}
+
+	return index[T]{}, false
+}
+
+// orderIndex returns the index of the provided name that items can be ordered
+// by, including bitmap indexes
+func (c *Collection[T]) orderIndex(name string) (index[T], bool) {
+	if idx, ok := c.bitmapIndex(name); ok {
+		return index[T]{kind: c.kind, key: idx.name, ref: idx.ref}, true
+	}
+
+	return c.indexByName(name)
+}
+
+// plan evaluates the provided predicate into the keys of the items it selects
+func (c *Collection[T]) plan(viewer DBViewer, pred Predicate) (p *queryPlan, err error) {
+	if c.bitmapOnly(pred) {
+		return c.planBitmap(viewer, pred)
+	}
+
+	p = &queryPlan{keys: map[string]struct{}{}}
+
+	switch pred.op {
+	case "":
+		p.desc = fmt.Sprintf("full scan of %s", c.kind)
+		c.scan(viewer, func(key string) { p.keys[key] = struct{}{} })
+	case "where":
+		err = c.planWhere(viewer, pred, p)
+	case "or":
+		p.desc = "or"
+		for _, child := range pred.children {
+			var cp *queryPlan
+			if cp, err = c.plan(viewer, child); err != nil {
+				return
+			}
+
+			p.children = append(p.children, cp)
+			for key := range cp.keys {
+				p.keys[key] = struct{}{}
+			}
+		}
+	case "and":
+		err = c.planAnd(viewer, pred, p)
+	case "not":
+		var cp *queryPlan
+		if cp, err = c.plan(viewer, pred.children[0]); err != nil {
+			return
+		}
+
+		p.desc = fmt.Sprintf("not (full scan of %s)", c.kind)
+		p.children = []*queryPlan{cp}
+		c.scan(viewer, func(key string) {
+			if _, ok := cp.keys[key]; !ok {
+				p.keys[key] = struct{}{}
+			}
+		})
+	default:
+		err = fmt.Errorf("invalid predicate")
+	}
+
+	return
+}
+
+// planAnd intersects the keys of the positive predicates, starting from the
+// smallest, and subtracts the keys of the negated ones, so the universe is
+// scanned only if all the predicates are negated
+func (c *Collection[T]) planAnd(viewer DBViewer, pred Predicate, p *queryPlan) (err error) {
+	p.desc = "and"
+
+	var positives, negatives []*queryPlan
+	for _, child := range pred.children {
+		var cp *queryPlan
+		if child.op == "not" {
+			if cp, err = c.plan(viewer, child.children[0]); err != nil {
+				return
+			}
+
+			cp.desc = "except " + cp.desc
+			p.children = append(p.children, cp)
+			negatives = append(negatives, cp)
+
+			continue
+		}
+
+		if cp, err = c.plan(viewer, child); err != nil {
+			return
+		}
+
+		p.children = append(p.children, cp)
+		positives = append(positives, cp)
+	}
+
+	if len(positives) == 0 {
+		p.desc = fmt.Sprintf("and (full scan of %s)", c.kind)
+		c.scan(viewer, func(key string) { p.keys[key] = struct{}{} })
+	} else {
+		sorted := slices.Clone(positives)
+		slices.SortFunc(sorted, func(a, b *queryPlan) int { return len(a.keys) - len(b.keys) })
+
+	keys:
+		for key := range sorted[0].keys {
+			for _, other := range sorted[1:] {
+				if _, ok := other.keys[key]; !ok {
+					continue keys
+				}
+			}
+
+			p.keys[key] = struct{}{}
+		}
+	}
+
+	for _, neg := range negatives {
+		for key := range neg.keys {
+			delete(p.keys, key)
+		}
+	}
+
+	return
+}
+
+func (c *Collection[T]) planWhere(viewer DBViewer, pred Predicate, p *queryPlan) error {
+	idx, ok := c.indexByName(pred.index)
+	if !ok {
+		return fmt.Errorf("collection %q has no index %q", c.kind, pred.index)
+	}
+
+	kind := "index"
+	if idx.pk {
+		kind = "primary key"
+	}
+
+	add := func(key string) { p.keys[key] = struct{}{} }
+
+	if pred.match.op != "between" {
+		p.desc = fmt.Sprintf("%s %s %s", kind, idx.key, pred.match)
+
+		for _, v := range pred.match.vals {
+			if idx.pk {
+				if _, ok := viewer.Get(mkKey(c.kind, idx.key, v)); ok {
+					add(mkKey(c.kind, idx.key, v))
+				}
+
+				continue
+			}
+
+			viewer.Iter(mkKey(c.kind, idx.key, v), func(key string, _ func() (any, bool)) bool {
+				add(key)
+				return true
+			})
+		}
+
+		return nil
+	}
+
+	from, to := pred.match.vals[0], pred.match.vals[1]
+	inRange := func(v string) bool { return v >= from && v <= to }
+	prefix := c.kind + string(curlyStart) + idx.key + string(colon)
+
+	p.desc = fmt.Sprintf("%s %s range scan %s", kind, idx.key, pred.match)
+
+	if idx.pk {
+		viewer.Iter(c.kind, func(key string, _ func() (any, bool)) bool {
+			if v, ok := indexValue(key, prefix); ok && inRange(v) {
+				add(key)
+			}
+
+			return true
+		})
+
+		return nil
+	}
+
+	var tags []string
+	indexValues(viewer, c.kind, idx.key, func(v, tag string, _ int) bool {
+		if inRange(v) {
+			tags = append(tags, tag)
+		}
+
+		return true
+	})
+
+	for _, tag := range tags {
+		viewer.Iter(tag, func(key string, _ func() (any, bool)) bool {
+			add(key)
+			return true
+		})
+	}
+
+	return nil
+}
+
+// scan calls fn with the keys of all the items of the collection
+func (c *Collection[T]) scan(viewer DBViewer, fn func(key string)) {
+	prefix := c.kind + string(curlyStart) + c.pk.key + string(colon)
+
+	viewer.Iter(c.kind, func(key string, _ func() (any, bool)) bool {
+		if _, ok := indexValue(key, prefix); ok {
+			fn(key)
+		}
+
+		return true
+	})
+}
+
+// indexValue returns the value of the provided index key or tag, if it has
+// the provided prefix of kind and index
+func indexValue(key, prefix string) (string, bool) {
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, string(curlyEnd)) {
+		return "", false
+	}
+
+	return key[len(prefix) : len(key)-1], true
+}
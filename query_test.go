@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	col := NewCollection[*pagedBook](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*pagedBook)) error {
+			load(
+				&pagedBook{book{"1", "Dune", "Frank Herbert"}, 412},
+				&pagedBook{book{"2", "Dune Messiah", "Frank Herbert"}, 256},
+				&pagedBook{book{"3", "Children of Dune", "Frank Herbert"}, 444},
+				&pagedBook{book{"4", "Hunters of Dune", "Brian Herbert"}, 496},
+				&pagedBook{book{"5", "Foundation", "Isaac Asimov"}, 255},
+			)
+			return nil
+		}),
+		PrimaryKey("id", func(b *pagedBook, val func(string)) { val(b.ID) }),
+		AdditionalKey("title", func(b *pagedBook, val func(string)) { val(b.Title) }),
+	)
+	col.MapBy("author", func(b *pagedBook, val func(string)) { val(b.Author) })
+	col.MapBy("pages", func(b *pagedBook, val func(string)) { val(fmt.Sprintf("%04d", b.Pages)) })
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ids := func(s Selection[*pagedBook]) (res []string) {
+		items, err := s.All()
+		assert.NoError(t, err)
+
+		for _, item := range items {
+			res = append(res, item.ID)
+		}
+
+		return
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, ids(col.Select(Where("author", Eq("Frank Herbert")))))
+	assert.Equal(t, []string{"2", "5"}, ids(col.Select(Where("id", In("2", "5", "9")))))
+	assert.Equal(t, []string{"2", "3", "4"}, ids(col.Select(Where("id", Between("2", "4")))))
+	assert.Equal(t, []string{"4", "5"}, ids(col.Select(Where("title", In("Foundation", "Hunters of Dune")))))
+
+	assert.Equal(t, []string{"1", "3"}, ids(col.Select(And(
+		Where("author", Eq("Frank Herbert")),
+		Where("pages", Between("0400", "0450")),
+	))))
+
+	assert.Equal(t, []string{"1", "3", "5"}, ids(col.Select(Or(
+		Where("author", Eq("Isaac Asimov")),
+		And(Where("author", Eq("Frank Herbert")), Not(Where("id", Eq("2")))),
+	))))
+
+	assert.Equal(t, []string{"4", "5"}, ids(col.Select(Not(Where("author", Eq("Frank Herbert"))))))
+	assert.Equal(t, []string{"4", "5"}, ids(col.Select(And(Not(Where("author", Eq("Frank Herbert")))))))
+
+	assert.Equal(t, []string{"4", "3"}, ids(col.Select(Where("author", In("Frank Herbert", "Brian Herbert"))).
+		OrderBy("pages", true).
+		Limit(2)))
+
+	assert.Equal(t, []string{"5", "2"}, ids(col.Select(Predicate{}).OrderBy("pages", false).Limit(2)))
+	assert.Equal(t, []string{"1", "2"}, ids(col.Select(Predicate{}).Limit(2)))
+
+	assert.Equal(t, []string{"3"}, ids(col.Select(Where("author", Eq("Frank Herbert"))).
+		Filter(func(b *pagedBook) bool { return b.Pages > 420 })))
+
+	plan, err := col.Select(And(
+		Where("author", Eq("Frank Herbert")),
+		Where("pages", Between("0400", "0450")),
+		Not(Where("title", Eq("Dune"))),
+	)).OrderBy("pages", true).Limit(1).Explain()
+	assert.NoError(t, err)
+	assert.Equal(t, `and (1 keys)
+  index author = "Frank Herbert" (3 keys)
+  index pages range scan between "0400" and "0450" (2 keys)
+  except index title = "Dune" (1 keys)
+order by pages desc
+limit 1
+`, plan)
+
+	plan, err = col.Select(Not(Where("id", In("1", "2")))).Explain()
+	assert.NoError(t, err)
+	assert.Equal(t, `not (full scan of books) (3 keys)
+  primary key id in ["1" "2"] (2 keys)
+`, plan)
+
+	_, err = col.Select(Where("isbn", Eq("0441013597"))).All()
+	assert.EqualError(t, err, `collection "books" has no index "isbn"`)
+
+	_, err = col.Select(Predicate{}).OrderBy("isbn", false).All()
+	assert.EqualError(t, err, `collection "books" has no index "isbn"`)
+}
@@ -0,0 +1,189 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RefViolation is a value of a foreign key that is referenced by items of a
+// collection but is missing from the referenced collection
+type RefViolation struct {
+	Index string `json:"index"`
+	Value string `json:"value"`
+
+	// Keys are the primary key values of the referencing items
+	Keys []string `json:"keys"`
+}
+
+func (v RefViolation) String() string {
+	return fmt.Sprintf("%d items reference missing %s=%q (%s)", len(v.Keys), v.Index, v.Value, strings.Join(v.Keys, ", "))
+}
+
+// Relation relates the items of a child collection to the items of a parent
+// collection by a foreign key, which is an index of the child collection whose
+// values are values of an index of the parent collection. the referential
+// integrity of a Relation that is created by Join is checked after every full
+// reload or rollback of either collection
+type Relation[Child, Parent any] struct {
+	child    *Collection[Child]
+	fk       index[Child]
+	parent   *Collection[Parent]
+	key      index[Parent]
+	getByKey Getter[Parent]
+
+	mu         sync.Mutex
+	violations []RefViolation
+}
+
+// Join creates a Relation between the provided collections by the provided
+// foreign key index of the child collection, which references the provided
+// index of the parent collection. both indexes must already exist
+func Join[Child, Parent any](child *Collection[Child], fk string, parent *Collection[Parent], key string) (*Relation[Child, Parent], error) {
+	r, err := relate(child, fk, parent, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// changes of some of the items don't trigger a check, since it scans all
+	// the values of the foreign key
+	child.fullyLoaded = append(child.fullyLoaded, r.check)
+	parent.fullyLoaded = append(parent.fullyLoaded, r.check)
+
+	return r, nil
+}
+
+// relate creates a Relation whose integrity isn't checked
+func relate[Child, Parent any](child *Collection[Child], fk string, parent *Collection[Parent], key string) (*Relation[Child, Parent], error) {
+	fkIdx, ok := child.indexByName(fk)
+	if !ok || fkIdx.pk {
+		return nil, fmt.Errorf("collection %q has no secondary index %q", child.kind, fk)
+	}
+
+	keyIdx, ok := parent.indexByName(key)
+	if !ok {
+		return nil, fmt.Errorf("collection %q has no index %q", parent.kind, key)
+	}
+
+	r := &Relation[Child, Parent]{
+		child:    child,
+		fk:       fkIdx,
+		parent:   parent,
+		key:      keyIdx,
+		getByKey: parent.GetBy(key),
+	}
+
+	return r, nil
+}
+
+// BelongsTo creates a func that returns the parent of an item of the child
+// collection by the provided foreign key. the integrity of the relation isn't
+// checked, Join checks it
+func BelongsTo[Child, Parent any](child *Collection[Child], fk string, parent *Collection[Parent], key string) (func(Child) (Parent, bool), error) {
+	r, err := relate(child, fk, parent, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Parent, nil
+}
+
+// HasMany creates a func that returns the children of an item of the parent
+// collection that reference it by the provided foreign key. the integrity of
+// the relation isn't checked, Join checks it
+func HasMany[Parent, Child any](parent *Collection[Parent], key string, child *Collection[Child], fk string) (func(Parent) ([]Child, error), error) {
+	r, err := relate(child, fk, parent, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Children, nil
+}
+
+// Parent returns the parent of the provided child
+func (r *Relation[Child, Parent]) Parent(child Child) (parent Parent, ok bool) {
+	r.fk.ref(child, func(v string) {
+		if !ok {
+			parent, ok = r.getByKey(v)
+		}
+	})
+
+	return
+}
+
+// Children returns the children of the provided parent, ordered by their keys
+func (r *Relation[Child, Parent]) Children(parent Parent) ([]Child, error) {
+	var vals []string
+	r.key.ref(parent, func(v string) {
+		vals = append(vals, v)
+	})
+
+	if len(vals) == 0 {
+		return nil, nil
+	}
+
+	return r.child.Select(Where(r.fk.key, In(vals...))).All()
+}
+
+// Violations returns the values of the foreign key that were referenced by
+// children but were missing from the parent collection when the integrity of
+// the relation was last checked
+func (r *Relation[Child, Parent]) Violations() []RefViolation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.violations
+}
+
+// check checks the referential integrity of the relation and logs the
+// violations
+func (r *Relation[Child, Parent]) check() {
+	var violations []RefViolation
+
+	_ = r.child.db.View(func(viewer DBViewer) error {
+		// both collections are read from a single snapshot if they share the db
+		parents := DBViewer(r.parent.db)
+		if r.parent.db == r.child.db {
+			parents = viewer
+		}
+
+		pkPrefix := r.child.kind + string(curlyStart) + r.child.pk.key + string(colon)
+
+		var values []string
+		indexValues(viewer, r.child.kind, r.fk.key, func(v, _ string, _ int) bool {
+			values = append(values, v)
+			return true
+		})
+		sort.Strings(values)
+
+		for _, v := range values {
+			tag := mkKey(r.child.kind, r.fk.key, v)
+			if _, ok := r.parent.get(parents, r.key.key, r.key.pk, v); ok {
+				continue
+			}
+
+			violation := RefViolation{Index: r.fk.key, Value: v}
+			viewer.Iter(tag, func(key string, _ func() (any, bool)) bool {
+				if pk, ok := indexValue(key, pkPrefix); ok {
+					violation.Keys = append(violation.Keys, pk)
+				}
+
+				return true
+			})
+			sort.Strings(violation.Keys)
+
+			violations = append(violations, violation)
+		}
+
+		return nil
+	})
+
+	for _, v := range violations {
+		r.child.log().Warn("referential integrity violation", "kind", r.child.kind, "references", r.parent.kind, "index", v.Index, "value", v.Value, "keys", v.Keys)
+	}
+
+	r.mu.Lock()
+	r.violations = violations
+	r.mu.Unlock()
+}
@@ -0,0 +1,129 @@
+package inventory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type author struct {
+	ID, Name string
+}
+
+type authoredBook struct {
+	ID, Title, AuthorID string
+}
+
+func TestJoin(t *testing.T) {
+	var (
+		db      = NewDB()
+		logger  = slog.New(slog.NewTextHandler(io.Discard, nil))
+		authors = []*author{{"herbert", "Frank Herbert"}, {"asimov", "Isaac Asimov"}, {"le-guin", "Ursula K. Le Guin"}}
+		books   = []*authoredBook{
+			{"1", "Dune", "herbert"},
+			{"2", "Dune Messiah", "herbert"},
+			{"3", "Foundation", "asimov"},
+			{"4", "The Caves of Steel", "asimov"},
+			{"5", "Hyperion", "simmons"},
+		}
+	)
+
+	authorsCol := NewCollection[*author](db, "authors",
+		Source(func(ctx context.Context, load func(in ...*author)) error {
+			load(authors...)
+			return nil
+		}),
+		PrimaryKey("id", func(a *author, val func(string)) { val(a.ID) }),
+		Logger[*author](logger),
+	)
+
+	booksCol := NewCollection[*authoredBook](db, "books",
+		Source(func(ctx context.Context, load func(in ...*authoredBook)) error {
+			load(books...)
+			return nil
+		}),
+		KeyedSource(func(ctx context.Context, vals []string, load func(in ...*authoredBook)) error {
+			for _, b := range books {
+				for _, v := range vals {
+					if b.ID == v {
+						load(b)
+					}
+				}
+			}
+			return nil
+		}),
+		PrimaryKey("id", func(b *authoredBook, val func(string)) { val(b.ID) }),
+		Logger[*authoredBook](logger),
+	)
+	booksCol.MapBy("author", func(b *authoredBook, val func(string)) { val(b.AuthorID) })
+
+	authorOf, err := BelongsTo(booksCol, "author", authorsCol, "id")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	booksOf, err := HasMany(authorsCol, "id", booksCol, "author")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rel, err := Join(booksCol, "author", authorsCol, "id")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = authorsCol.Reload(context.Background())
+	assert.NoError(t, err)
+	_, err = booksCol.Reload(context.Background())
+	assert.NoError(t, err)
+
+	a, ok := authorOf(books[0])
+	assert.True(t, ok)
+	assert.Equal(t, "Frank Herbert", a.Name)
+
+	_, ok = authorOf(books[4])
+	assert.False(t, ok)
+
+	res, err := booksOf(authors[1])
+	assert.NoError(t, err)
+	assert.Equal(t, []*authoredBook{books[2], books[3]}, res)
+
+	res, err = booksOf(authors[2])
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	assert.Equal(t, []RefViolation{{"author", "simmons", []string{"5"}}}, rel.Violations())
+	assert.Equal(t, `1 items reference missing author="simmons" (5)`, rel.Violations()[0].String())
+
+	// the integrity is checked after a reload of the parent collection too
+	authors = authors[1:]
+	_, err = authorsCol.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []RefViolation{
+		{"author", "herbert", []string{"1", "2"}},
+		{"author", "simmons", []string{"5"}},
+	}, rel.Violations())
+
+	// but not after reloading specific keys, until the next full reload
+	books[4].AuthorID = "asimov"
+	_, err = booksCol.ReloadKeys(context.Background(), "5")
+	assert.NoError(t, err)
+	assert.Len(t, rel.Violations(), 2)
+
+	_, err = booksCol.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []RefViolation{{"author", "herbert", []string{"1", "2"}}}, rel.Violations())
+
+	// the relations of BelongsTo and HasMany aren't checked
+	assert.Len(t, booksCol.fullyLoaded, 1)
+	assert.Len(t, authorsCol.fullyLoaded, 1)
+
+	_, err = Join(booksCol, "publisher", authorsCol, "id")
+	assert.EqualError(t, err, `collection "books" has no secondary index "publisher"`)
+
+	_, err = Join(booksCol, "author", authorsCol, "name")
+	assert.EqualError(t, err, `collection "authors" has no index "name"`)
+}
@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetryPolicy defines how failed extractions from the origin source are
+// retried. failures of the extracted data, such as violations or invalid
+// items, are not retried
+type RetryPolicy struct {
+	// Attempts is the max number of attempts of every reload
+	Attempts int
+
+	// Backoff is the delay between attempts
+	Backoff Backoff
+
+	// Timeout limits the duration of every attempt. zero means no limit
+	Timeout time.Duration
+}
+
+// Retry retries reloads of the collection whose extraction failed according
+// to the provided policy
+func Retry[T any](policy RetryPolicy) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.retry = policy
+	}
+}
+
+// BreakerState is the state of the circuit breaker of a Collection
+type BreakerState string
+
+const (
+	// BreakerClosed lets reloads hit the origin source
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen fails reloads without hitting the origin source
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets a single trial reload hit the origin source
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// ErrCircuitOpen is returned by reloads of a collection whose circuit breaker
+// is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreaker stops hitting the origin source of the collection once the
+// provided number of consecutive reloads failed to extract from it. the
+// current items continue to be served while the breaker is open. once the
+// provided cooldown passes, a single trial reload closes the breaker if it
+// succeeds or opens it again if it fails
+func CircuitBreaker[T any](failures int, cooldown time.Duration) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.breaker = &breaker{
+			threshold: max(failures, 1),
+			cooldown:  cooldown,
+			state:     BreakerClosed,
+		}
+	}
+}
+
+// breaker is a circuit breaker over the origin source of a collection
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// allow reports whether the origin source may be hit
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+
+		b.state = BreakerHalfOpen
+
+		return true
+	case BreakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record records the outcome of hitting the origin source
+func (b *breaker) record(failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.state = BreakerClosed
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+func (b *breaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// extractError is a failure of the origin source, as opposed to a failure of
+// the extracted data
+type extractError struct {
+	err error
+}
+
+func (e *extractError) Error() string {
+	return e.err.Error()
+}
+
+func (e *extractError) Unwrap() error {
+	return e.err
+}
+
+// attempt calls fn, which hits the origin source, according to the retry
+// policy and the circuit breaker of the collection
+func (c *Collection[T]) attempt(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if c.breaker != nil && !c.breaker.allow(time.Now()) {
+		return fmt.Errorf("failed to reload %q: %w", c.kind, ErrCircuitOpen)
+	}
+
+	var xErr *extractError
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.retry.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.retry.Timeout)
+		}
+
+		err = fn(attemptCtx)
+		cancel()
+
+		if !errors.As(err, &xErr) || attempt >= c.retry.Attempts || ctx.Err() != nil {
+			break
+		}
+
+		c.mu.Lock()
+		c.stats.Retries++
+		c.mu.Unlock()
+
+		c.log().Warn("retrying failed extraction", "kind", c.kind, "attempt", attempt, "error", err)
+		if sleep(ctx, c.retry.Backoff.Delay(attempt)) != nil {
+			break
+		}
+	}
+
+	if c.breaker != nil {
+		c.breaker.record(errors.As(err, &xErr), time.Now())
+	}
+
+	return
+}
@@ -0,0 +1,142 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	var (
+		calls int
+		fail  int
+		block bool
+	)
+
+	col := NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			calls++
+			if block {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			if calls <= fail {
+				return errors.New("connection refused")
+			}
+
+			load(&book{"1", "Dune", "Frank Herbert"})
+
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		Retry[*book](RetryPolicy{Attempts: 3, Backoff: Backoff{Initial: time.Millisecond}, Timeout: 10 * time.Millisecond}),
+		Logger[*book](slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+
+	fail = 2
+	res, err := col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Items)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 2, col.Stats().Retries)
+
+	calls, fail = 0, 3
+	_, err = col.Reload(context.Background())
+	assert.EqualError(t, err, `failed to extract "books": connection refused`)
+	assert.Equal(t, 3, calls)
+
+	calls, fail, block = 0, 0, true
+	_, err = col.Reload(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 3, calls)
+
+	_, ok := col.GetBy("id")("1")
+	assert.True(t, ok)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		calls   int
+		fail    bool
+		invalid bool
+	)
+
+	col := NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			calls++
+			if fail {
+				return errors.New("connection refused")
+			}
+
+			if invalid {
+				load(&book{ID: "1"})
+			} else {
+				load(&book{"1", "Dune", "Frank Herbert"})
+			}
+
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		Validate(func(b *book) error {
+			if b.Title == "" {
+				return errors.New("missing title")
+			}
+			return nil
+		}),
+		CircuitBreaker[*book](2, 20*time.Millisecond),
+	)
+	byID := col.GetBy("id")
+
+	_, err := col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerClosed, col.Stats().Breaker)
+
+	// failures of the extracted data don't open the breaker
+	invalid = true
+	for i := 0; i < 2; i++ {
+		_, err = col.Reload(context.Background())
+		assert.ErrorContains(t, err, "missing title")
+	}
+	assert.Equal(t, BreakerClosed, col.Stats().Breaker)
+
+	invalid, fail = false, true
+	for i := 0; i < 2; i++ {
+		_, err = col.Reload(context.Background())
+		assert.ErrorContains(t, err, "connection refused")
+	}
+	assert.Equal(t, BreakerOpen, col.Stats().Breaker)
+
+	calls = 0
+	_, err = col.Reload(context.Background())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 0, calls)
+
+	b, ok := byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune", b.Title)
+
+	// a failed trial opens the breaker again
+	time.Sleep(20 * time.Millisecond)
+	_, err = col.Reload(context.Background())
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, BreakerOpen, col.Stats().Breaker)
+
+	_, err = col.Reload(context.Background())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	fail = false
+	time.Sleep(20 * time.Millisecond)
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerClosed, col.Stats().Breaker)
+
+	_, err = col.ReloadKeys(context.Background(), "1")
+	assert.EqualError(t, err, `collection "books" has no keyed source`)
+}
@@ -0,0 +1,161 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// namedSource is one of the sources of a collection that is assembled from
+// multiple sources
+type namedSource[T any] struct {
+	name     string
+	priority int
+	extract  ExtractFunc[T]
+}
+
+// sources are the sources of a collection along with the items that were
+// last extracted from each one of them, so a single source can be reloaded
+// without extracting the others
+type sources[T any] struct {
+	list  []namedSource[T]
+	merge func(low, high T) T
+
+	mu     sync.Mutex
+	loaded map[string][]T
+}
+
+type onlySourceKey struct{}
+
+// NamedSource adds a source of the collection. the items of all the sources
+// are merged by their primary key, in the order of the priorities of their
+// sources. by default, the item of the source of the highest priority wins,
+// unless a MergeFunc is set
+func NamedSource[T any](name string, priority int, x ExtractFunc[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.sources.list = append(c.sources.list, namedSource[T]{name, priority, x})
+		slices.SortStableFunc(c.sources.list, func(a, b namedSource[T]) int { return a.priority - b.priority })
+
+		c.extract = c.extractSources
+	}
+}
+
+// MergeFunc sets the func that merges the items of the same primary key from
+// the sources of the collection, such as merging them field by field. it is
+// called with the merged item of the lower priority sources and the item of
+// the next source
+func MergeFunc[T any](merge func(low, high T) T) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.sources.merge = merge
+	}
+}
+
+// ReloadSource reloads the collection by extracting only the provided source,
+// and merging its items with the items that were last extracted from the
+// other sources
+func (c *Collection[T]) ReloadSource(ctx context.Context, name string) (ReloadResult, error) {
+	if !slices.ContainsFunc(c.sources.list, func(s namedSource[T]) bool { return s.name == name }) {
+		return ReloadResult{}, fmt.Errorf("collection %q has no source %q", c.kind, name)
+	}
+
+	return c.Reload(context.WithValue(ctx, onlySourceKey{}, name))
+}
+
+// extractSources is the ExtractFunc of a collection with named sources
+func (c *Collection[T]) extractSources(ctx context.Context, load func(in ...T)) error {
+	_, err := c.mergeSources(ctx, load)
+
+	return err
+}
+
+// mergeSources extracts the sources of the collection and loads their items
+// merged by primary key. the items of each source are deduplicated by the
+// constraint policy of the collection before they are merged, and the
+// violations are returned
+func (c *Collection[T]) mergeSources(ctx context.Context, load func(in ...T)) (violations []Violation, err error) {
+	if c.pk.ref == nil {
+		return nil, fmt.Errorf("collection %q has no primary key", c.kind)
+	}
+
+	only, _ := ctx.Value(onlySourceKey{}).(string)
+
+	// reloads are serialized, so the snapshot is of the last committed reload
+	c.sources.mu.Lock()
+	loaded := make(map[string][]T, len(c.sources.list))
+	for name, items := range c.sources.loaded {
+		loaded[name] = items
+	}
+	c.sources.mu.Unlock()
+
+	modified := false
+	for _, s := range c.sources.list {
+		if _, ok := loaded[s.name]; ok && only != "" && only != s.name {
+			continue
+		}
+
+		var items []T
+		err = s.extract(ctx, func(in ...T) {
+			items = append(items, in...)
+		})
+
+		switch {
+		case errors.Is(err, ErrNotModified):
+			if _, ok := loaded[s.name]; ok {
+				continue
+			}
+
+			return nil, fmt.Errorf("source %q is not modified but was never extracted", s.name)
+		case err != nil:
+			return nil, fmt.Errorf("source %q: %w", s.name, err)
+		}
+
+		loaded[s.name] = items
+		modified = true
+	}
+
+	if !modified {
+		return nil, ErrNotModified
+	}
+
+	AfterCommit(ctx, func() {
+		c.sources.mu.Lock()
+		c.sources.loaded = loaded
+		c.sources.mu.Unlock()
+	})
+
+	var (
+		keys   []string
+		merged = map[string]T{}
+	)
+
+	for _, s := range c.sources.list {
+		var staged []stagedItem[T]
+		c.indexer(loaded[s.name], func(key string, item T) {
+			staged = append(staged, stagedItem[T]{key, item})
+		})
+
+		items, dups := c.dedupe(staged)
+		violations = append(violations, dups...)
+
+		for _, s := range items {
+			item := s.item
+			prev, ok := merged[s.key]
+			switch {
+			case !ok:
+				keys = append(keys, s.key)
+			case c.sources.merge != nil:
+				item = c.sources.merge(prev, item)
+			}
+
+			merged[s.key] = item
+		}
+	}
+
+	for _, key := range keys {
+		load(merged[key])
+	}
+
+	return
+}
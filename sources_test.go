@@ -0,0 +1,201 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedSources(t *testing.T) {
+	var (
+		rowsCalls, overridesCalls int
+		overridesErr              error
+		rows                      = []*book{
+			{"1", "Dune", "Frank Herbert"},
+			{"2", "Dune Messiah", "Frank Herbert"},
+		}
+		overrides = []*book{
+			{"2", "", "F. Herbert"},
+			{"3", "Foundation", "Isaac Asimov"},
+		}
+	)
+
+	newCol := func(opts ...CollectionOpt[*book]) *Collection[*book] {
+		return NewCollection[*book](NewDB(), "books", append([]CollectionOpt[*book]{
+			NamedSource("overrides", 10, func(ctx context.Context, load func(in ...*book)) error {
+				overridesCalls++
+				if overridesErr != nil {
+					return overridesErr
+				}
+
+				load(overrides...)
+				return nil
+			}),
+			NamedSource("rows", 0, func(ctx context.Context, load func(in ...*book)) error {
+				rowsCalls++
+				load(rows...)
+				return nil
+			}),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+			Logger[*book](slog.New(slog.NewTextHandler(io.Discard, nil))),
+		}, opts...)...)
+	}
+
+	t.Run("last wins by priority", func(t *testing.T) {
+		col := newCol()
+		byID := col.GetBy("id")
+
+		_, err := col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		b, _ := byID("2")
+		assert.Equal(t, &book{"2", "", "F. Herbert"}, b)
+		assert.Equal(t, 3, col.CountAll())
+	})
+
+	t.Run("merge func", func(t *testing.T) {
+		rowsCalls, overridesCalls = 0, 0
+
+		col := newCol(MergeFunc(func(low, high *book) *book {
+			merged := *low
+			if high.Title != "" {
+				merged.Title = high.Title
+			}
+			if high.Author != "" {
+				merged.Author = high.Author
+			}
+
+			return &merged
+		}))
+		byID := col.GetBy("id")
+
+		_, err := col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		b, _ := byID("2")
+		assert.Equal(t, &book{"2", "Dune Messiah", "F. Herbert"}, b)
+
+		// reloading a source recomputes the merged items without extracting
+		// the other sources
+		overrides = []*book{{"1", "Dune (40th Anniversary Edition)", ""}}
+		res, err := col.ReloadSource(context.Background(), "overrides")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Items)
+		assert.Equal(t, 1, rowsCalls)
+		assert.Equal(t, 2, overridesCalls)
+
+		b, _ = byID("1")
+		assert.Equal(t, &book{"1", "Dune (40th Anniversary Edition)", "Frank Herbert"}, b)
+		b, _ = byID("2")
+		assert.Equal(t, &book{"2", "Dune Messiah", "Frank Herbert"}, b)
+
+		// a failed reload of a source keeps its last extracted items
+		overridesErr = errors.New("file not found")
+		_, err = col.ReloadSource(context.Background(), "overrides")
+		assert.ErrorContains(t, err, `source "overrides": file not found`)
+
+		overridesErr = nil
+		rows = rows[1:]
+		_, err = col.ReloadSource(context.Background(), "rows")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, overridesCalls)
+
+		b, _ = byID("1")
+		assert.Equal(t, &book{"1", "Dune (40th Anniversary Edition)", ""}, b)
+
+		// a source that isn't modified keeps its last extracted items
+		overridesErr = ErrNotModified
+		res, err = col.ReloadSource(context.Background(), "overrides")
+		assert.NoError(t, err)
+		assert.True(t, res.Unchanged)
+
+		_, err = col.ReloadSource(context.Background(), "isbns")
+		assert.EqualError(t, err, `collection "books" has no source "isbns"`)
+	})
+
+	t.Run("duplicates within a source", func(t *testing.T) {
+		overridesErr = nil
+		rows = []*book{{"1", "Dune", "Frank Herbert"}, {"1", "Dune Messiah", "Frank Herbert"}}
+		overrides = []*book{{"1", "", "F. Herbert"}}
+
+		col := newCol(OnViolation[*book](KeepFirst), MergeFunc(func(low, high *book) *book {
+			return &book{low.ID, low.Title, high.Author}
+		}))
+
+		res, err := col.Reload(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []Violation{{"id", "1", []string{"1"}, 2}}, res.Violations)
+
+		b, _ := col.GetBy("id")("1")
+		assert.Equal(t, &book{"1", "Dune", "F. Herbert"}, b)
+
+		_, err = newCol(OnViolation[*book](FailOnViolation)).Reload(context.Background())
+		var violationErr *ViolationError
+		assert.ErrorAs(t, err, &violationErr)
+	})
+}
+
+func TestNamedSourcesConcurrentReloads(t *testing.T) {
+	var (
+		extracted [2]atomic.Int64
+		committed = make(chan struct{})
+	)
+
+	source := func(i int) ExtractFunc[*book] {
+		return func(ctx context.Context, load func(in ...*book)) error {
+			n := extracted[i].Add(1)
+			if i == 1 && n == 2 {
+				// delays the commit of the reload past the start of the next one
+				AfterCommit(ctx, func() {
+					close(committed)
+					time.Sleep(10 * time.Millisecond)
+				})
+			}
+
+			load(&book{ID: strconv.Itoa(i), Title: strconv.FormatInt(n, 10)})
+
+			return nil
+		}
+	}
+
+	col := NewCollection[*book](NewDB(), "books",
+		NamedSource("0", 0, source(0)),
+		NamedSource("1", 1, source(1)),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+	)
+	byID := col.GetBy("id")
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := col.ReloadSource(context.Background(), "1")
+		done <- err
+	}()
+
+	<-committed
+	_, err = col.ReloadSource(context.Background(), "0")
+	assert.NoError(t, err)
+	assert.NoError(t, <-done)
+
+	// neither reload replaced the items that the other one extracted
+	_, err = col.ReloadSource(context.Background(), "1")
+	assert.NoError(t, err)
+
+	for i := range extracted {
+		b, ok := byID(strconv.Itoa(i))
+		if assert.True(t, ok) {
+			assert.Equal(t, strconv.FormatInt(extracted[i].Load(), 10), b.Title)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// NewSQLExtractor creates an SQLExtractor of T that extracts the items by the
+// provided query
+func NewSQLExtractor[T any](db *sql.DB, query string, opts ...SQLOpt) *SQLExtractor[T] {
+	x := &SQLExtractor[T]{
+		db:    db,
+		query: query,
+		sqlOptions: sqlOptions{
+			batchSize: 100,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&x.sqlOptions)
+	}
+
+	return x
+}
+
+// SQLExtractor extracts items of T, which is either a struct or a pointer to
+// a struct, from a database/sql db. every column of the query's result is
+// scanned into the field of T that is mapped to it by SQLColumns, by the
+// `sql` struct tag or by the name of the field, regardless of case and
+// underscores.
+//
+// its Extract and ExtractKeys methods are meant to be used as the Source and
+// the KeyedSource of a Collection:
+//
+//	x := NewSQLExtractor[*book](db, "select id, title from books", SQLKeyQuery("select id, title from books where id in ({keys})"))
+//	books := NewCollection[*book](db, "books", Source(x.Extract), KeyedSource(x.ExtractKeys), ...)
+type SQLExtractor[T any] struct {
+	sqlOptions
+
+	db    *sql.DB
+	query string
+}
+
+// SQLOpt is an option for instrumenting an SQLExtractor
+type SQLOpt func(*sqlOptions)
+
+type sqlOptions struct {
+	args        []any
+	keyQuery    string
+	placeholder func(i int) string
+	columns     map[string]string
+	batchSize   int
+}
+
+// keysMarker is replaced by the placeholders of the keys in the key query
+const keysMarker = "{keys}"
+
+// SQLArgs sets the args of the query of the SQLExtractor
+func SQLArgs(args ...any) SQLOpt {
+	return func(o *sqlOptions) {
+		o.args = args
+	}
+}
+
+// SQLKeyQuery sets the query for extracting items by their primary key
+// values. a "{keys}" marker in the query, like in "where id in ({keys})", is
+// replaced by a placeholder for every key, so the keys are extracted by a
+// single query per batch. without the marker, the query accepts a single key
+// as its only arg and is executed once per key
+func SQLKeyQuery(query string) SQLOpt {
+	return func(o *sqlOptions) {
+		o.keyQuery = query
+	}
+}
+
+// SQLPlaceholder sets the func that returns the placeholder of the i-th arg,
+// from 1, of the key query. the default is "?". for postgres, for example,
+// it is "$1", "$2" and so on
+func SQLPlaceholder(placeholder func(i int) string) SQLOpt {
+	return func(o *sqlOptions) {
+		o.placeholder = placeholder
+	}
+}
+
+// SQLColumns maps columns to the names of the fields they are scanned into.
+// unmapped columns are scanned by the `sql` struct tag or by field names
+func SQLColumns(mapping map[string]string) SQLOpt {
+	return func(o *sqlOptions) {
+		o.columns = mapping
+	}
+}
+
+// SQLBatchSize sets the max number of items that are loaded at once. the
+// default is 100
+func SQLBatchSize(n int) SQLOpt {
+	return func(o *sqlOptions) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// Extract extracts all the items by the query of the SQLExtractor
+func (x *SQLExtractor[T]) Extract(ctx context.Context, load func(in ...T)) error {
+	return x.extract(ctx, load, x.query, x.args...)
+}
+
+// ExtractKeys extracts the items of the provided primary key values by the
+// key query of the SQLExtractor, in batches of up to the batch size of keys
+func (x *SQLExtractor[T]) ExtractKeys(ctx context.Context, keys []string, load func(in ...T)) (err error) {
+	if x.keyQuery == "" {
+		return fmt.Errorf("sql extractor has no key query")
+	}
+
+	if !strings.Contains(x.keyQuery, keysMarker) {
+		for _, key := range keys {
+			if err = x.extract(ctx, load, x.keyQuery, key); err != nil {
+				return
+			}
+		}
+
+		return
+	}
+
+	for from := 0; from < len(keys); from += x.batchSize {
+		batch := keys[from:min(from+x.batchSize, len(keys))]
+
+		placeholders := make([]string, len(batch))
+		args := make([]any, len(batch))
+		for i, key := range batch {
+			placeholders[i] = "?"
+			if x.placeholder != nil {
+				placeholders[i] = x.placeholder(i + 1)
+			}
+
+			args[i] = key
+		}
+
+		query := strings.Replace(x.keyQuery, keysMarker, strings.Join(placeholders, ", "), 1)
+		if err = x.extract(ctx, load, query, args...); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (x *SQLExtractor[T]) extract(ctx context.Context, load func(in ...T), query string, args ...any) (err error) {
+	f, err := newFields[T]("sql")
+	if err != nil {
+		return
+	}
+
+	rows, err := x.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+
+	index, err := f.resolve(columns, x.columns)
+	if err != nil {
+		return
+	}
+
+	var (
+		batch = make([]T, 0, x.batchSize)
+		dest  = make([]any, len(columns))
+		row   int
+	)
+
+	for rows.Next() {
+		row++
+
+		item, v := f.new()
+		for i := range index {
+			dest[i] = f.field(v, index[i]).Addr().Interface()
+		}
+
+		if err = rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan row %d: %w", row, err)
+		}
+
+		batch = append(batch, item())
+		if len(batch) == x.batchSize {
+			load(batch...)
+			batch = make([]T, 0, x.batchSize)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if len(batch) > 0 {
+		load(batch...)
+	}
+
+	return
+}
@@ -0,0 +1,218 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSQLDriver serves static tables by query. the rows of a query with args
+// are filtered by their first column
+type fakeSQLDriver struct {
+	tables  map[string]fakeTable
+	queries []string
+}
+
+type fakeTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d *fakeSQLDriver) Open(string) (driver.Conn, error) {
+	return d, nil
+}
+
+func (d *fakeSQLDriver) Prepare(query string) (driver.Stmt, error) {
+	table, ok := d.tables[query]
+	if !ok {
+		return nil, fmt.Errorf("unknown query %q", query)
+	}
+
+	d.queries = append(d.queries, query)
+
+	return &fakeStmt{table}, nil
+}
+
+func (d *fakeSQLDriver) Close() error {
+	return nil
+}
+
+func (d *fakeSQLDriver) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+type fakeStmt struct {
+	table fakeTable
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{columns: s.table.columns}
+	for _, row := range s.table.rows {
+		if len(args) == 0 || slices.Contains(args, row[0]) {
+			rows.rows = append(rows.rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+type pagedBook struct {
+	book
+
+	Pages int `sql:"page_count"`
+}
+
+func TestSQLExtractor(t *testing.T) {
+	fake := &fakeSQLDriver{tables: map[string]fakeTable{
+		"select * from books": {
+			columns: []string{"id", "book_title", "author", "page_count"},
+			rows: [][]driver.Value{
+				{"1", "Dune", "Frank Herbert", int64(412)},
+				{"2", "Dune Messiah", "Frank Herbert", int64(256)},
+				{"3", "Children of Dune", "Frank Herbert", int64(444)},
+			},
+		},
+		"select * from books where id in (?, ?)": {
+			columns: []string{"id", "book_title", "author", "page_count"},
+			rows: [][]driver.Value{
+				{"1", "Dune", "Frank Herbert", int64(412)},
+				{"2", "Dune Messiah (2nd edition)", "Frank Herbert", int64(256)},
+			},
+		},
+		"select id, year from books": {
+			columns: []string{"id", "year"},
+			rows:    [][]driver.Value{{"1", int64(1965)}},
+		},
+		"select id, page_count from books": {
+			columns: []string{"id", "page_count"},
+			rows:    [][]driver.Value{{"1", int64(412)}, {"2", "many"}},
+		},
+	}}
+
+	driverName := fmt.Sprintf("inventory-fake-%p", fake)
+	sql.Register(driverName, fake)
+
+	sqlDB, err := sql.Open(driverName, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sqlDB.Close()
+
+	x := NewSQLExtractor[*pagedBook](sqlDB, "select * from books",
+		SQLColumns(map[string]string{"book_title": "Title"}),
+		SQLKeyQuery("select * from books where id in ({keys})"),
+		SQLBatchSize(2),
+	)
+
+	var batches [][]*pagedBook
+	err = x.Extract(context.Background(), func(in ...*pagedBook) {
+		batches = append(batches, in)
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, batches, 2) {
+		// batches that are kept by the caller aren't overwritten
+		assert.Len(t, batches[0], 2)
+		assert.Equal(t, "1", batches[0][0].ID)
+		assert.Len(t, batches[1], 1)
+		assert.Equal(t, "3", batches[1][0].ID)
+	}
+
+	col := NewCollection[*pagedBook](NewDB(), "books",
+		Source(x.Extract),
+		KeyedSource(x.ExtractKeys),
+		PrimaryKey("id", func(b *pagedBook, val func(string)) { val(b.ID) }),
+	)
+	byID := col.GetBy("id")
+
+	res, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, res.Items)
+
+	b, ok := byID("2")
+	assert.True(t, ok)
+	assert.Equal(t, &pagedBook{book{"2", "Dune Messiah", "Frank Herbert"}, 256}, b)
+
+	fake.queries = nil
+	res, err = col.ReloadKeys(context.Background(), "2", "3")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, res.Items)
+	assert.Equal(t, []string{"select * from books where id in (?, ?)"}, fake.queries)
+
+	b, ok = byID("2")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune Messiah (2nd edition)", b.Title)
+
+	_, ok = byID("3")
+	assert.False(t, ok)
+
+	b, ok = byID("1")
+	assert.True(t, ok)
+	assert.Equal(t, 412, b.Pages)
+
+	assert.Equal(t, 2, col.count())
+
+	err = NewSQLExtractor[*pagedBook](sqlDB, "select id, year from books").
+		Extract(context.Background(), func(in ...*pagedBook) {})
+	assert.EqualError(t, err, `inventory.pagedBook has no field for "year"`)
+
+	var loaded int
+	err = NewSQLExtractor[pagedBook](sqlDB, "select id, page_count from books").
+		Extract(context.Background(), func(in ...pagedBook) { loaded += len(in) })
+	assert.ErrorContains(t, err, "failed to scan row 2")
+	assert.Equal(t, 0, loaded)
+
+	col = NewCollection[*pagedBook](NewDB(), "books",
+		Source(NewSQLExtractor[*pagedBook](sqlDB, "select id, page_count from books").Extract),
+		PrimaryKey("id", func(b *pagedBook, val func(string)) { val(b.ID) }),
+	)
+
+	_, err = col.Reload(context.Background())
+	assert.ErrorContains(t, err, `failed to extract "books": failed to scan row 2`)
+	assert.Equal(t, 0, col.count())
+}
@@ -0,0 +1,369 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ChangeOp is the operation of a Change
+type ChangeOp int
+
+const (
+	// Upsert puts the item of the change, replacing the item of the same
+	// primary key
+	Upsert ChangeOp = iota
+
+	// Delete deletes the item of the primary key of the change
+	Delete
+)
+
+// Change is a change of a single item in the origin source
+type Change[T any] struct {
+	// Seq is the sequence number of the change in the stream. it grows with
+	// every change
+	Seq uint64
+
+	Op ChangeOp
+
+	// Item is the upserted item
+	Item T
+
+	// Key is the primary key value of the deleted item
+	Key string
+}
+
+// ErrStreamGap is returned by a ChangeStream when the changes that follow the
+// requested sequence number are no longer available
+var ErrStreamGap = errors.New("gap in change stream")
+
+// ChangeStream streams the changes of the origin source of a Collection, so
+// they are applied incrementally instead of reloading all data
+type ChangeStream[T any] interface {
+	// Head returns the sequence number of the last change in the stream
+	Head(ctx context.Context) (uint64, error)
+
+	// Changes returns the next changes that follow the provided sequence
+	// number, in order. it blocks until there is at least one change or ctx
+	// is done. it returns ErrStreamGap if some of the following changes are
+	// no longer available
+	Changes(ctx context.Context, after uint64) ([]Change[T], error)
+}
+
+// OffsetStore persists the sequence number of the last change that was
+// applied to every kind, so following a stream resumes from it after restart
+type OffsetStore interface {
+	// LoadOffset returns the stored offset of the provided kind
+	LoadOffset(kind string) (seq uint64, ok bool, err error)
+
+	// SaveOffset stores the offset of the provided kind
+	SaveOffset(kind string, seq uint64) error
+}
+
+// FollowOpt is an option for instrumenting the following of a ChangeStream
+type FollowOpt func(*followOptions)
+
+type followOptions struct {
+	offsets OffsetStore
+}
+
+// FollowOffsets sets the store of the offset of the stream
+func FollowOffsets(store OffsetStore) FollowOpt {
+	return func(o *followOptions) {
+		o.offsets = store
+	}
+}
+
+// Follow applies the changes of the provided stream to the collection until
+// ctx is done. every batch of changes is committed in a single update and
+// constraints are checked only among its items.
+//
+// the stream is followed from the stored offset, if there is one, or from its
+// head after a full reload. the full reload also precedes resuming from a
+// stored offset if the collection wasn't loaded yet, since replaying changes
+// that were already reloaded is harmless. a gap in the stream falls back to a
+// full reload from the head of the stream. a collection without a Source
+// follows the stream from its beginning and fails on gaps
+func (c *Collection[T]) Follow(ctx context.Context, stream ChangeStream[T], opts ...FollowOpt) (err error) {
+	o := followOptions{offsets: &MemoryOffsets{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	seq, ok, err := o.offsets.LoadOffset(c.kind)
+	if err != nil {
+		return fmt.Errorf("failed to load offset of %q: %w", c.kind, err)
+	}
+
+	loaded := c.Stats().Generation > 0
+	switch {
+	case c.extract == nil || (ok && loaded):
+	case ok:
+		if _, err = c.Reload(ctx); err != nil {
+			return
+		}
+	default:
+		if seq, err = c.resync(ctx, stream); err != nil {
+			return
+		}
+	}
+
+	// the offset is stored after every change of it, so a restart resumes
+	// from it and not from before the reloads that preceded it
+	advance := func(to uint64) error {
+		seq = to
+		if err := o.offsets.SaveOffset(c.kind, seq); err != nil {
+			return fmt.Errorf("failed to save offset of %q: %w", c.kind, err)
+		}
+
+		c.setOffset(seq)
+		return nil
+	}
+
+	if err = advance(seq); err != nil {
+		return
+	}
+
+	for {
+		var changes []Change[T]
+		changes, err = stream.Changes(ctx, seq)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, ErrStreamGap) && c.extract != nil {
+			c.log().Warn("reloading after gap in change stream", "kind", c.kind, "offset", seq)
+			var head uint64
+			if head, err = c.resync(ctx, stream); err != nil {
+				return
+			}
+
+			if err = advance(head); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to stream changes of %q after %d: %w", c.kind, seq, err)
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		if _, err = c.Apply(changes...); err != nil {
+			return
+		}
+
+		if err = advance(changes[len(changes)-1].Seq); err != nil {
+			return
+		}
+	}
+}
+
+// resync reloads all data and returns the head of the stream as of before the
+// reload
+func (c *Collection[T]) resync(ctx context.Context, stream ChangeStream[T]) (head uint64, err error) {
+	if head, err = stream.Head(ctx); err != nil {
+		return 0, fmt.Errorf("failed to get the head of the change stream of %q: %w", c.kind, err)
+	}
+
+	_, err = c.Reload(ctx)
+
+	return
+}
+
+func (c *Collection[T]) setOffset(seq uint64) {
+	c.mu.Lock()
+	c.stats.Offset = seq
+	c.mu.Unlock()
+}
+
+// Apply applies the provided changes, in order, in a single update. only the
+// last change of every primary key takes effect. constraints are checked only
+// among the upserted items
+func (c *Collection[T]) Apply(changes ...Change[T]) (res ReloadResult, err error) {
+	// changes are serialized with reloads, so a reload that extracted before
+	// them doesn't commit over them
+	c.reloading.Lock()
+	defer c.reloading.Unlock()
+
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	var (
+		keys   []string
+		last   = map[string]int{}
+		staged []stagedItem[T]
+	)
+
+	for _, change := range changes {
+		switch change.Op {
+		case Upsert:
+			c.indexer([]T{change.Item}, func(key string, item T) {
+				last[key] = len(staged)
+				keys = append(keys, key)
+				staged = append(staged, stagedItem[T]{key, item})
+			})
+		case Delete:
+			key := mkKey(c.kind, c.pk.key, change.Key)
+			last[key] = -1
+			keys = append(keys, key)
+		default:
+			return res, fmt.Errorf("unknown op %d of change %d", change.Op, change.Seq)
+		}
+	}
+
+	var upserts []stagedItem[T]
+	for i, s := range staged {
+		if last[s.key] == i {
+			upserts = append(upserts, s)
+			keys = append(keys, c.absentKeys(s.item)...)
+		}
+	}
+
+	res, delta, err := c.replace(keys, upserts)
+	if err != nil {
+		if len(changes) > 0 {
+			err = fmt.Errorf("failed to apply changes %d-%d of %q: %w", changes[0].Seq, changes[len(changes)-1].Seq, c.kind, err)
+		}
+
+		return
+	}
+
+	c.committed(c.patch(keys, delta), start)
+
+	return
+}
+
+// MemoryOffsets is an OffsetStore in memory
+type MemoryOffsets struct {
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+// LoadOffset returns the stored offset of the provided kind
+func (m *MemoryOffsets) LoadOffset(kind string) (seq uint64, ok bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seq, ok = m.offsets[kind]
+
+	return
+}
+
+// SaveOffset stores the offset of the provided kind
+func (m *MemoryOffsets) SaveOffset(kind string, seq uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.offsets == nil {
+		m.offsets = map[string]uint64{}
+	}
+
+	m.offsets[kind] = seq
+
+	return nil
+}
+
+// NewMemoryStream creates a MemoryStream that retains the provided number of
+// its last changes. a non-positive retain keeps all changes
+func NewMemoryStream[T any](retain int) *MemoryStream[T] {
+	return &MemoryStream[T]{
+		retain:  retain,
+		changed: make(chan struct{}),
+	}
+}
+
+// MemoryStream is a ChangeStream in memory. changes that exceed its retention
+// are discarded, so followers that fall behind them get ErrStreamGap
+type MemoryStream[T any] struct {
+	retain int
+
+	mu      sync.Mutex
+	seq     uint64
+	changes []Change[T]
+	changed chan struct{}
+}
+
+// Upsert appends an upsert change for every provided item and returns the
+// sequence number of the last one
+func (s *MemoryStream[T]) Upsert(items ...T) uint64 {
+	changes := make([]Change[T], len(items))
+	for i, item := range items {
+		changes[i] = Change[T]{Op: Upsert, Item: item}
+	}
+
+	return s.append(changes)
+}
+
+// Delete appends a delete change for every provided primary key value and
+// returns the sequence number of the last one
+func (s *MemoryStream[T]) Delete(keys ...string) uint64 {
+	changes := make([]Change[T], len(keys))
+	for i, key := range keys {
+		changes[i] = Change[T]{Op: Delete, Key: key}
+	}
+
+	return s.append(changes)
+}
+
+func (s *MemoryStream[T]) append(changes []Change[T]) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range changes {
+		s.seq++
+		changes[i].Seq = s.seq
+	}
+
+	s.changes = append(s.changes, changes...)
+	if n := len(s.changes) - s.retain; s.retain > 0 && n > 0 {
+		s.changes = append(s.changes[:0:0], s.changes[n:]...)
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return s.seq
+}
+
+// Head returns the sequence number of the last change in the stream
+func (s *MemoryStream[T]) Head(context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seq, nil
+}
+
+// Changes returns all the changes that follow the provided sequence number
+func (s *MemoryStream[T]) Changes(ctx context.Context, after uint64) ([]Change[T], error) {
+	for {
+		s.mu.Lock()
+		first := s.seq + 1 - uint64(len(s.changes))
+		if after+1 < first || after > s.seq {
+			s.mu.Unlock()
+			return nil, ErrStreamGap
+		}
+
+		if after < s.seq {
+			res := append([]Change[T](nil), s.changes[after+1-first:]...)
+			s.mu.Unlock()
+
+			return res, nil
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
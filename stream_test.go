@@ -0,0 +1,281 @@
+package inventory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionFollow(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		books   = map[string]*book{"1": {"1", "Dune", "Frank Herbert"}}
+		reloads int
+	)
+
+	// upsert mirrors the stream's changes in the source of the full reloads
+	upsert := func(b *book) *book {
+		mu.Lock()
+		books[b.ID] = b
+		mu.Unlock()
+
+		return b
+	}
+
+	newCol := func() *Collection[*book] {
+		return NewCollection[*book](NewDB(), "books",
+			Source(func(ctx context.Context, load func(in ...*book)) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				reloads++
+				for _, b := range books {
+					load(b)
+				}
+
+				return nil
+			}),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		)
+	}
+
+	reloaded := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return reloads
+	}
+
+	stream := NewMemoryStream[*book](2)
+	offsets := &MemoryOffsets{}
+
+	follow := func(col *Collection[*book]) (stop func() error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+
+		go func() { done <- col.Follow(ctx, stream, FollowOffsets(offsets)) }()
+
+		return func() error {
+			cancel()
+			return <-done
+		}
+	}
+
+	waitFor := func(col *Collection[*book], seq uint64) {
+		assert.Eventually(t, func() bool { return col.Stats().Offset == seq }, time.Second, time.Millisecond)
+	}
+
+	stream.Upsert(upsert(&book{"2", "Dune Messiah", "Frank Herbert"}))
+
+	col := newCol()
+	byID := col.GetBy("id")
+	stop := follow(col)
+
+	waitFor(col, 1)
+	assert.Equal(t, 1, reloaded())
+
+	// the offset of the reload is stored before any change is applied
+	stored := func() uint64 {
+		seq, ok, err := offsets.LoadOffset("books")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		return seq
+	}
+	assert.Equal(t, uint64(1), stored())
+
+	_, ok := byID("2")
+	assert.True(t, ok)
+
+	seq := stream.Upsert(upsert(&book{"3", "Children of Dune", "Frank Herbert"}))
+	waitFor(col, seq)
+
+	seq = stream.Delete("1")
+	waitFor(col, seq)
+
+	_, ok = byID("1")
+	assert.False(t, ok)
+
+	b, ok := byID("3")
+	assert.True(t, ok)
+	assert.Equal(t, "Children of Dune", b.Title)
+	assert.Equal(t, 1, reloaded())
+
+	assert.NoError(t, stop())
+
+	// resume from the stored offset, without a reload
+	seq = stream.Upsert(&book{"4", "God Emperor of Dune", "Frank Herbert"})
+	stop = follow(col)
+	waitFor(col, seq)
+	assert.NoError(t, stop())
+
+	_, ok = byID("4")
+	assert.True(t, ok)
+	assert.Equal(t, 1, reloaded())
+
+	// the stream falls behind its retention while not followed
+	stream.Upsert(upsert(&book{"5", "Heretics of Dune", "Frank Herbert"}))
+	stream.Upsert(upsert(&book{"6", "Chapterhouse: Dune", "Frank Herbert"}))
+	seq = stream.Upsert(upsert(&book{"7", "Hunters of Dune", "Brian Herbert"}))
+
+	stop = follow(col)
+	waitFor(col, seq)
+	assert.Equal(t, 2, reloaded())
+	assert.Equal(t, seq, stored())
+
+	// a full reload of the source doesn't have the item of change 4
+	_, ok = byID("4")
+	assert.False(t, ok)
+
+	_, ok = byID("6")
+	assert.True(t, ok)
+
+	seq = stream.Upsert(upsert(&book{"8", "Sandworms of Dune", "Brian Herbert"}))
+	waitFor(col, seq)
+	assert.NoError(t, stop())
+
+	// a restarted process reloads before resuming from the stored offset
+	restarted := newCol()
+	stop = follow(restarted)
+	waitFor(restarted, seq)
+	assert.NoError(t, stop())
+
+	assert.Equal(t, 3, reloaded())
+	_, ok = restarted.GetBy("id")("8")
+	assert.True(t, ok)
+}
+
+func TestCollectionApply(t *testing.T) {
+	col := NewCollection[*book](NewDB(), "books",
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		UniqueKey("title", func(b *book, val func(string)) { val(b.Title) }),
+		OnViolation[*book](FailOnViolation),
+		KeepGenerations[*book](2),
+	)
+	byID := col.GetBy("id")
+	byTitle := col.GetBy("title")
+
+	var reloaded int
+	col.reloaded = append(col.reloaded, func() { reloaded++ })
+
+	res, err := col.Apply(
+		Change[*book]{Seq: 1, Op: Upsert, Item: &book{"1", "Dune", "Frank Herbert"}},
+		Change[*book]{Seq: 2, Op: Upsert, Item: &book{"2", "Dune Messiah", "Frank Herbert"}},
+		Change[*book]{Seq: 3, Op: Delete, Key: "2"},
+		Change[*book]{Seq: 4, Op: Upsert, Item: &book{"1", "Dune (1965)", "Frank Herbert"}},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, res.Items)
+
+	_, ok := byID("2")
+	assert.False(t, ok)
+
+	_, ok = byTitle("Dune")
+	assert.False(t, ok)
+
+	b, ok := byTitle("Dune (1965)")
+	assert.True(t, ok)
+	assert.Equal(t, "1", b.ID)
+
+	// applied changes are followed by the same funcs as a reload
+	assert.Equal(t, 1, reloaded)
+	assert.Equal(t, 1, col.Stats().Items)
+	assert.Len(t, col.Generations(), 1)
+
+	_, err = col.Apply(Change[*book]{Seq: 5, Op: Upsert, Item: &book{"2", "Dune Messiah", "Frank Herbert"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, col.Stats().Items)
+	if gens := col.Generations(); assert.Len(t, gens, 2) {
+		assert.Equal(t, 2, gens[1].Items)
+	}
+
+	_, err = col.Rollback(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, col.Stats().Items)
+
+	_, ok = byID("2")
+	assert.False(t, ok)
+
+	_, ok = byID("1")
+	assert.True(t, ok)
+
+	_, err = col.Apply(
+		Change[*book]{Seq: 5, Op: Upsert, Item: &book{"3", "Dune", "Frank Herbert"}},
+		Change[*book]{Seq: 6, Op: Upsert, Item: &book{"4", "Dune", "Frank Herbert"}},
+	)
+	assert.ErrorContains(t, err, `failed to apply changes 5-6 of "books"`)
+
+	_, ok = byID("3")
+	assert.False(t, ok)
+	assert.Equal(t, 3, reloaded)
+
+	// an upsert forgets the absences of the values of the item
+	col.markAbsent("id", "5", time.Now())
+	col.markAbsent("title", "Heretics of Dune", time.Now())
+
+	_, err = col.Apply(Change[*book]{Seq: 7, Op: Upsert, Item: &book{"5", "Heretics of Dune", "Frank Herbert"}})
+	assert.NoError(t, err)
+
+	_, ok = col.db.Get("books{!id:5}")
+	assert.False(t, ok)
+
+	_, ok = col.db.Get("books{!title:Heretics of Dune}")
+	assert.False(t, ok)
+
+	// changes wait for the reload in progress
+	done := make(chan struct{})
+	col.reloading.Lock()
+	go func() {
+		defer close(done)
+
+		_, err := col.Apply(Change[*book]{Seq: 8, Op: Delete, Key: "5"})
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("changes were applied during the reload")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	col.reloading.Unlock()
+	<-done
+
+	_, ok = byID("5")
+	assert.False(t, ok)
+}
+
+func TestMemoryStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s := NewMemoryStream[string](2)
+
+	head, err := s.Head(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), head)
+
+	go s.Upsert("a", "b", "c")
+
+	changes, err := s.Changes(ctx, 0)
+	assert.ErrorIs(t, err, ErrStreamGap)
+	assert.Nil(t, changes)
+
+	changes, err = s.Changes(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change[string]{{Seq: 2, Item: "b"}, {Seq: 3, Item: "c"}}, changes)
+
+	_, err = s.Changes(ctx, 4)
+	assert.ErrorIs(t, err, ErrStreamGap)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer waitCancel()
+
+	_, err = s.Changes(waitCtx, 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,289 @@
+package inventory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Hit is an item that is found by a text search along with its score
+type Hit[T any] struct {
+	Item  T
+	Score float64
+}
+
+// TextOpt configures a text index
+type TextOpt func(*textOptions)
+
+type textOptions struct {
+	stem func(string) string
+	stop map[string]struct{}
+}
+
+// TextStemmer sets a func that reduces every token to its stem, like
+// StemEnglish. by default, tokens aren't stemmed
+func TextStemmer(stem func(string) string) TextOpt {
+	return func(o *textOptions) {
+		o.stem = stem
+	}
+}
+
+// TextStopWords sets words that are not indexed nor searched, like
+// EnglishStopWords
+func TextStopWords(words ...string) TextOpt {
+	return func(o *textOptions) {
+		for _, w := range words {
+			o.stop[normalize(w)] = struct{}{}
+		}
+	}
+}
+
+// EnglishStopWords are common english words that are usually not worth
+// indexing
+var EnglishStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "by", "for", "from", "in", "is",
+	"it", "of", "on", "or", "that", "the", "to", "was", "with",
+}
+
+// StemEnglish is a naive stemmer that strips common english suffixes
+func StemEnglish(token string) string {
+	for _, suffix := range []string{"ingly", "edly", "ing", "ies", "ed", "ly", "s"} {
+		stem, ok := strings.CutSuffix(token, suffix)
+		if !ok || len([]rune(stem)) < 3 || strings.HasSuffix(stem, "s") {
+			continue
+		}
+
+		if suffix == "ies" {
+			stem += "y"
+		}
+
+		return stem
+	}
+
+	return token
+}
+
+type textIndex[T any] struct {
+	name string
+	ref  indexFn[T]
+	textOptions
+}
+
+// textDoc is the entry of an item in a text index with the frequencies of its
+// terms
+type textDoc struct {
+	item   string
+	terms  map[string]int
+	length int
+}
+
+// TextIndex adds a full-text index of the collection over the text that is
+// emitted by the provided func. the text is tokenized into an inverted index
+// that is searched by Search
+func TextIndex[T any](name string, text indexFn[T], opts ...TextOpt) CollectionOpt[T] {
+	idx := textIndex[T]{name: name, ref: text, textOptions: textOptions{stop: map[string]struct{}{}}}
+	for _, opt := range opts {
+		opt(&idx.textOptions)
+	}
+
+	return func(c *Collection[T]) {
+		c.texts = append(c.texts, idx)
+	}
+}
+
+// Search ranks the items of the provided text index by their relevance to the
+// provided query, using BM25, and returns the first limit of them. the
+// ranking is computed on a single snapshot of the db, so all the hits belong
+// to the same generation of the collection
+func (c *Collection[T]) Search(index, query string, limit int) (hits []Hit[T], err error) {
+	var idx *textIndex[T]
+	for i := range c.texts {
+		if c.texts[i].name == index {
+			idx = &c.texts[i]
+		}
+	}
+
+	if idx == nil {
+		return nil, fmt.Errorf("collection %q has no text index %q", c.kind, index)
+	}
+
+	const k1, b = 1.2, 0.75
+
+	err = c.db.View(func(viewer DBViewer) error {
+		docs := textDocs(c.kind, idx.name)
+
+		var total int
+		viewer.Iter(docs, func(_ string, getVal func() (any, bool)) bool {
+			if i, ok := getVal(); ok {
+				total += i.(textDoc).length
+			}
+
+			return true
+		})
+
+		n := viewer.Count(docs)
+		if n == 0 {
+			return nil
+		}
+
+		avg := float64(total) / float64(n)
+
+		scores := map[string]float64{}
+		seen := map[string]struct{}{}
+		idx.tokenize(query, func(term string) {
+			if _, ok := seen[term]; ok {
+				return
+			}
+			seen[term] = struct{}{}
+
+			tag := textTerm(c.kind, idx.name, term)
+			df := float64(viewer.Count(tag))
+			idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+
+			viewer.Iter(tag, func(_ string, getVal func() (any, bool)) bool {
+				i, ok := getVal()
+				if !ok {
+					return true
+				}
+
+				doc := i.(textDoc)
+				tf := float64(doc.terms[term])
+				scores[doc.item] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(doc.length)/avg))
+
+				return true
+			})
+		})
+
+		keys := make([]string, 0, len(scores))
+		for key := range scores {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			if scores[keys[i]] != scores[keys[j]] {
+				return scores[keys[i]] > scores[keys[j]]
+			}
+
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			if limit > 0 && len(hits) == limit {
+				break
+			}
+
+			i, ok := viewer.Get(key)
+			if !ok {
+				continue
+			}
+
+			t, ok := i.(T)
+			if !ok {
+				return fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+			}
+
+			hits = append(hits, Hit[T]{t, scores[key]})
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// indexText adds the item of the provided key to the text indexes of the
+// collection. the entries are tagged with the key of the item, so they are
+// deleted along with it
+func (c *Collection[T]) indexText(writer DBWriter, key string, item T) {
+	for _, idx := range c.texts {
+		doc := textDoc{item: key, terms: map[string]int{}}
+		idx.ref(item, func(text string) {
+			idx.tokenize(text, func(term string) {
+				doc.terms[term]++
+				doc.length++
+			})
+		})
+
+		docKey := mkKey(c.kind, "#"+idx.name, key)
+		writer.Put(docKey, doc)
+		writer.Tag(docKey, c.kind, key, textDocs(c.kind, idx.name))
+
+		for term := range doc.terms {
+			writer.Tag(docKey, textTerm(c.kind, idx.name, term))
+		}
+	}
+}
+
+// textDocs is the tag of all the entries of a text index
+func textDocs(kind, name string) string {
+	return kind + string(curlyStart) + "#" + name + string(curlyEnd)
+}
+
+// textTerm is the tag of the entries of a text index that contain the
+// provided term
+func textTerm(kind, name, term string) string {
+	return mkKey(kind, "~"+name, term)
+}
+
+// tokenize splits the provided text to normalized terms, without the stop
+// words
+func (idx *textIndex[T]) tokenize(text string, fn func(term string)) {
+	for _, token := range strings.FieldsFunc(normalize(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if _, ok := idx.stop[token]; ok {
+			continue
+		}
+
+		if idx.stem != nil {
+			token = idx.stem(token)
+		}
+
+		fn(token)
+	}
+}
+
+// folds maps latin letters with diacritics to their base letters
+var folds = func() map[rune]string {
+	m := map[rune]string{'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d"}
+	for base, letters := range map[string]string{
+		"a": "àáâãäåāăą",
+		"c": "çćĉċč",
+		"e": "èéêëēĕėęě",
+		"i": "ìíîïĩīĭįı",
+		"n": "ñńņňŉ",
+		"o": "òóôõöōŏő",
+		"u": "ùúûüũūŭůűų",
+		"y": "ýÿŷ",
+		"s": "śŝşš",
+		"z": "źżž",
+	} {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+
+	return m
+}()
+
+// normalize lowercases the provided text and folds its diacritics, either
+// precomposed or combining
+func normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if f, ok := folds[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
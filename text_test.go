@@ -0,0 +1,74 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	books := []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "Dune Messiah", "Frank Herbert"},
+		{"3", "Children of Dune", "Frank Herbert"},
+		{"4", "The Dune Encyclopedia", "Willis McNelly"},
+		{"5", "Les Misérables", "Victor Hugo"},
+		{"6", "The Children of Húrin", "J. R. R. Tolkien"},
+	}
+
+	col := NewCollection[*book](NewDB(), "books",
+		Source(func(ctx context.Context, load func(in ...*book)) error {
+			load(books...)
+			return nil
+		}),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		TextIndex("title", func(b *book, text func(string)) { text(b.Title) },
+			TextStemmer(StemEnglish),
+			TextStopWords(EnglishStopWords...),
+		),
+		TextIndex("author", func(b *book, text func(string)) { text(b.Author) }),
+	)
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ids := func(hits []Hit[*book], err error) (res []string) {
+		assert.NoError(t, err)
+		for _, hit := range hits {
+			res = append(res, hit.Item.ID)
+		}
+
+		return
+	}
+
+	// shorter titles rank higher
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids(col.Search("title", "dune", 0)))
+	assert.Equal(t, []string{"3", "6"}, ids(col.Search("title", "DUNE children", 2)))
+
+	// diacritics are folded and tokens are stemmed
+	assert.Equal(t, []string{"5"}, ids(col.Search("title", "miserable", 0)))
+	assert.Equal(t, []string{"6", "3"}, ids(col.Search("title", "hurin childrens", 0)))
+
+	// stop words aren't indexed
+	assert.Empty(t, ids(col.Search("title", "the of", 0)))
+
+	assert.Equal(t, []string{"6"}, ids(col.Search("author", "tolkien", 0)))
+	assert.Empty(t, ids(col.Search("author", "asimov", 0)))
+
+	_, err = col.Search("isbn", "0441013597", 0)
+	assert.EqualError(t, err, `collection "books" has no text index "isbn"`)
+
+	// reloading replaces the index
+	books = books[1:]
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "3", "4"}, ids(col.Search("title", "dune", 0)))
+
+	_, err = col.Apply(Change[*book]{Op: Upsert, Item: &book{"2", "Dune Messiah", "Brian Herbert"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, ids(col.Search("author", "brian", 0)))
+	assert.Equal(t, []string{"3"}, ids(col.Search("author", "frank", 0)))
+}
@@ -0,0 +1,258 @@
+package inventory
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TrieOpt configures a trie index
+type TrieOpt func(*trieOptions)
+
+type trieOptions struct {
+	sep      string
+	reversed bool
+}
+
+// TrieSeparator sets the separator of the segments of the values. by default,
+// values are paths separated by "/"
+func TrieSeparator(sep string) TrieOpt {
+	return func(o *trieOptions) {
+		o.sep = sep
+	}
+}
+
+// TrieDomain makes the trie index hold domain names, whose labels are
+// separated by "." and are matched from the last one, so a prefix of the trie
+// is a suffix of the name
+func TrieDomain() TrieOpt {
+	return func(o *trieOptions) {
+		o.sep = "."
+		o.reversed = true
+	}
+}
+
+// trieIndex is a hierarchical index of the collection. the items are tagged
+// by their values like any other index, and the trie of the values is built
+// from the tags of the index once per version of the collection
+type trieIndex[T any] struct {
+	name string
+	ref  indexFn[T]
+	trieOptions
+
+	mu      sync.Mutex
+	version uint64
+	root    *trieNode
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	values   []string
+}
+
+func (n *trieNode) insert(segments []string, value string) {
+	for _, s := range segments {
+		child, ok := n.children[s]
+		if !ok {
+			child = &trieNode{children: map[string]*trieNode{}}
+			n.children[s] = child
+		}
+
+		n = child
+	}
+
+	n.values = append(n.values, value)
+}
+
+// walk calls fn with the values of the node and all the nodes under it
+func (n *trieNode) walk(fn func(value string)) {
+	for _, v := range n.values {
+		fn(v)
+	}
+
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// TrieIndex adds a hierarchical index of the collection, for values like
+// paths or domain names, that is queried by LongestPrefix, WithPrefix and
+// Wildcard
+func TrieIndex[T any](name string, value indexFn[T], opts ...TrieOpt) CollectionOpt[T] {
+	idx := &trieIndex[T]{name: name, ref: value, trieOptions: trieOptions{sep: "/"}}
+	for _, opt := range opts {
+		opt(&idx.trieOptions)
+	}
+
+	return func(c *Collection[T]) {
+		c.tries = append(c.tries, idx)
+	}
+}
+
+// LongestPrefix returns the items of the longest value of the provided trie
+// index that is a prefix of the provided value, by whole segments
+func (c *Collection[T]) LongestPrefix(index, val string) ([]T, error) {
+	return c.matchTrie(index, func(idx *trieIndex[T], root *trieNode) (values []string) {
+		n := root
+		values = n.values
+
+		for _, s := range idx.segments(val) {
+			if n = n.children[s]; n == nil {
+				break
+			}
+
+			if len(n.values) > 0 {
+				values = n.values
+			}
+		}
+
+		return slices.Clone(values)
+	})
+}
+
+// WithPrefix returns the items whose values of the provided trie index are
+// under the provided prefix, by whole segments, including the prefix itself.
+// for domain names, these are the subdomains of the provided name
+func (c *Collection[T]) WithPrefix(index, prefix string) ([]T, error) {
+	return c.matchTrie(index, func(idx *trieIndex[T], root *trieNode) (values []string) {
+		n := root
+		for _, s := range idx.segments(prefix) {
+			if n = n.children[s]; n == nil {
+				return
+			}
+		}
+
+		n.walk(func(value string) { values = append(values, value) })
+
+		return
+	})
+}
+
+// Wildcard returns the items whose values of the provided trie index match the
+// provided pattern, in which a "*" segment matches any single segment and a
+// "**" segment matches any number of segments. for example, "*.example.com"
+// matches the direct subdomains of example.com
+func (c *Collection[T]) Wildcard(index, pattern string) ([]T, error) {
+	return c.matchTrie(index, func(idx *trieIndex[T], root *trieNode) (values []string) {
+		var match func(n *trieNode, pattern []string)
+		match = func(n *trieNode, pattern []string) {
+			if len(pattern) == 0 {
+				values = append(values, n.values...)
+
+				return
+			}
+
+			switch s := pattern[0]; s {
+			case "**":
+				match(n, pattern[1:])
+				for _, child := range n.children {
+					match(child, pattern)
+				}
+			case "*":
+				for _, child := range n.children {
+					match(child, pattern[1:])
+				}
+			default:
+				if child, ok := n.children[s]; ok {
+					match(child, pattern[1:])
+				}
+			}
+		}
+
+		match(root, idx.segments(pattern))
+
+		return
+	})
+}
+
+// matchTrie finds the values of the provided trie index by the provided func
+// and returns their items, all from the same snapshot of the db. an item with
+// several matching values is returned once
+func (c *Collection[T]) matchTrie(index string, find func(idx *trieIndex[T], root *trieNode) []string) (res []T, err error) {
+	i := slices.IndexFunc(c.tries, func(idx *trieIndex[T]) bool { return idx.name == index })
+	if i < 0 {
+		return nil, fmt.Errorf("collection %q has no trie index %q", c.kind, index)
+	}
+
+	idx := c.tries[i]
+
+	err = c.db.View(func(viewer DBViewer) error {
+		var keys []string
+		for _, v := range find(idx, idx.build(viewer, c.kind)) {
+			viewer.Iter(mkKey(c.kind, idx.name, v), func(key string, _ func() (any, bool)) bool {
+				keys = append(keys, key)
+				return true
+			})
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range slices.Compact(keys) {
+			i, ok := viewer.Get(key)
+			if !ok {
+				continue
+			}
+
+			t, ok := i.(T)
+			if !ok {
+				return fmt.Errorf("expected type %T for %q. got %T", t, key, i)
+			}
+
+			res = append(res, t)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// build returns the trie of the values of the index in the provided viewer,
+// which is rebuilt from the tags of the index if the collection has changed
+// since it was last built
+func (idx *trieIndex[T]) build(viewer DBViewer, kind string) *trieNode {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	version := viewer.TagVersion(itemsTag(kind))
+	if idx.root != nil && idx.version == version {
+		return idx.root
+	}
+
+	root := &trieNode{children: map[string]*trieNode{}}
+	indexValues(viewer, kind, idx.name, func(v, _ string, _ int) bool {
+		root.insert(idx.segments(v), v)
+		return true
+	})
+
+	idx.root, idx.version = root, version
+
+	return root
+}
+
+// segments splits the provided value to the segments of the trie
+func (idx *trieIndex[T]) segments(val string) (res []string) {
+	for _, s := range strings.Split(val, idx.sep) {
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+
+	if idx.reversed {
+		slices.Reverse(res)
+	}
+
+	return
+}
+
+// tagItemWithTries tags the item of the provided key with its values of the
+// trie indexes of the collection
+func (c *Collection[T]) tagItemWithTries(writer DBWriter, key string, item T) {
+	for _, idx := range c.tries {
+		idx.ref(item, func(v string) {
+			tagIndexValue(writer, key, c.kind, idx.name, v)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type route struct {
+	Path, Handler string
+}
+
+type zone struct {
+	Name, Server string
+	Aliases      []string
+}
+
+func TestTrieIndex(t *testing.T) {
+	t.Run("paths", func(t *testing.T) {
+		routes := []*route{
+			{"/", "root"},
+			{"/api", "api"},
+			{"/api/books", "books"},
+			{"/api/books/search", "search"},
+			{"/api/authors", "authors"},
+		}
+
+		col := NewCollection[*route](NewDB(), "routes",
+			Source(func(ctx context.Context, load func(in ...*route)) error {
+				load(routes...)
+				return nil
+			}),
+			PrimaryKey("handler", func(r *route, val func(string)) { val(r.Handler) }),
+			TrieIndex("path", func(r *route, val func(string)) { val(r.Path) }),
+		)
+
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		handlers := func(res []*route, err error) (handlers []string) {
+			assert.NoError(t, err)
+			for _, r := range res {
+				handlers = append(handlers, r.Handler)
+			}
+
+			return
+		}
+
+		assert.Equal(t, []string{"books"}, handlers(col.LongestPrefix("path", "/api/books/42")))
+		assert.Equal(t, []string{"api"}, handlers(col.LongestPrefix("path", "/api/booksellers")))
+		assert.Equal(t, []string{"root"}, handlers(col.LongestPrefix("path", "/static/app.js")))
+
+		assert.Equal(t, []string{"api", "authors", "books", "search"}, handlers(col.WithPrefix("path", "/api")))
+		assert.Equal(t, []string{"books", "search"}, handlers(col.WithPrefix("path", "/api/books/")))
+		assert.Empty(t, handlers(col.WithPrefix("path", "/static")))
+
+		assert.Equal(t, []string{"authors", "books"}, handlers(col.Wildcard("path", "/api/*")))
+		assert.Equal(t, []string{"search"}, handlers(col.Wildcard("path", "/**/search")))
+
+		_, err = col.LongestPrefix("url", "/")
+		assert.EqualError(t, err, `collection "routes" has no trie index "url"`)
+
+		// the trie is rebuilt with the next generation
+		routes = routes[:3]
+		_, err = col.Reload(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"books"}, handlers(col.LongestPrefix("path", "/api/books/search")))
+		assert.Equal(t, []string{"api", "books"}, handlers(col.WithPrefix("path", "/api")))
+	})
+
+	t.Run("domains", func(t *testing.T) {
+		col := NewCollection[*zone](NewDB(), "zones",
+			Source(func(ctx context.Context, load func(in ...*zone)) error {
+				load(
+					&zone{Name: "example.com", Server: "ns1"},
+					&zone{Name: "mail.example.com", Server: "ns2"},
+					&zone{Name: "eu.cdn.example.com", Server: "ns3"},
+					&zone{Name: "example.org", Server: "ns4"},
+					&zone{Name: "example.net", Server: "ns5", Aliases: []string{"www.example.net", "api.example.net"}},
+				)
+				return nil
+			}),
+			PrimaryKey("server", func(z *zone, val func(string)) { val(z.Server) }),
+			TrieIndex("name", func(z *zone, val func(string)) {
+				val(z.Name)
+				for _, alias := range z.Aliases {
+					val(alias)
+				}
+			}, TrieDomain()),
+		)
+
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		servers := func(res []*zone, err error) (servers []string) {
+			assert.NoError(t, err)
+			for _, z := range res {
+				servers = append(servers, z.Server)
+			}
+
+			return
+		}
+
+		assert.Equal(t, []string{"ns2"}, servers(col.LongestPrefix("name", "smtp.mail.example.com")))
+		assert.Equal(t, []string{"ns1"}, servers(col.LongestPrefix("name", "www.example.com")))
+		assert.Empty(t, servers(col.LongestPrefix("name", "example.io")))
+
+		assert.Equal(t, []string{"ns1", "ns2", "ns3"}, servers(col.WithPrefix("name", "example.com")))
+		assert.Equal(t, []string{"ns2"}, servers(col.Wildcard("name", "*.example.com")))
+		assert.Equal(t, []string{"ns2", "ns3"}, servers(col.Wildcard("name", "**.*.example.com")))
+		assert.Equal(t, []string{"ns1", "ns4", "ns5"}, servers(col.Wildcard("name", "example.*")))
+
+		// an item that matches by several of its values is returned once
+		assert.Equal(t, []string{"ns5"}, servers(col.WithPrefix("name", "example.net")))
+		assert.Equal(t, []string{"ns5"}, servers(col.Wildcard("name", "*.example.net")))
+	})
+}
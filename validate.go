@@ -0,0 +1,73 @@
+package inventory
+
+import (
+	"fmt"
+)
+
+// BatchValidator is a function for validating all the extracted items of a
+// collection as a whole before they replace the previous generation. previous
+// is the number of items in the previous generation
+type BatchValidator[T any] func(items []T, previous int) error
+
+// Validate adds a validation of every extracted item of the collection. an
+// invalid item fails the reload so the previous generation of the collection
+// continues to be served
+func Validate[T any](fn func(T) error) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.validators = append(c.validators, fn)
+	}
+}
+
+// ValidateBatch adds a validation of all the extracted items of the collection
+// as a whole. failing the validation fails the reload so the previous
+// generation of the collection continues to be served
+func ValidateBatch[T any](fn BatchValidator[T]) CollectionOpt[T] {
+	return func(c *Collection[T]) {
+		c.batchVals = append(c.batchVals, fn)
+	}
+}
+
+// MinRatio is a BatchValidator that requires the extracted items to be at
+// least the provided ratio of the previous generation. for example, 0.9 means
+// that a reload that loses more than 10% of the items is rejected
+func MinRatio[T any](ratio float64) BatchValidator[T] {
+	return func(items []T, previous int) error {
+		if float64(len(items)) < ratio*float64(previous) {
+			return fmt.Errorf("extracted %d items which is less than %g of the previous %d", len(items), ratio, previous)
+		}
+
+		return nil
+	}
+}
+
+func (c *Collection[T]) validateItems(staged []stagedItem[T]) (err error) {
+	for _, s := range staged {
+		for _, validate := range c.validators {
+			if err = validate(s.item); err != nil {
+				return fmt.Errorf("invalid item %q: %w", s.key, err)
+			}
+		}
+	}
+
+	return
+}
+
+func (c *Collection[T]) validateBatch(staged []stagedItem[T]) (err error) {
+	if len(c.batchVals) == 0 {
+		return
+	}
+
+	items := make([]T, len(staged))
+	for i, s := range staged {
+		items[i] = s.item
+	}
+
+	previous := c.count()
+	for _, validate := range c.batchVals {
+		if err = validate(items, previous); err != nil {
+			return fmt.Errorf("invalid batch of %q: %w", c.kind, err)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,69 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	books := []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "Dune Messiah", "Frank Herbert"},
+		{"3", "Children of Dune", "Frank Herbert"},
+	}
+
+	col := NewCollection[*book](NewDB(), "books",
+		Extractor(func(load func(in ...*book)) { load(books...) }),
+		PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		Validate(func(b *book) error {
+			if b.Title == "" {
+				return fmt.Errorf("missing title")
+			}
+
+			return nil
+		}),
+		ValidateBatch(MinRatio[*book](0.9)),
+	)
+	byID := col.GetBy("id")
+
+	_, err := col.Reload(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	books = []*book{
+		{"1", "Dune", "Frank Herbert"},
+		{"2", "", "Frank Herbert"},
+		{"3", "Children of Dune", "Frank Herbert"},
+	}
+
+	_, err = col.Reload(context.Background())
+	assert.EqualError(t, err, `invalid item "books{id:2}": missing title`)
+
+	b, ok := byID("2")
+	assert.True(t, ok)
+	assert.Equal(t, "Dune Messiah", b.Title)
+
+	books = books[:1]
+
+	_, err = col.Reload(context.Background())
+	assert.EqualError(t, err, `invalid batch of "books": extracted 1 items which is less than 0.9 of the previous 3`)
+
+	_, ok = byID("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3, col.Stats().Items)
+
+	books = append(books, &book{"2", "Dune Messiah", "Frank Herbert"}, &book{"4", "God Emperor of Dune", "Frank Herbert"})
+
+	_, err = col.Reload(context.Background())
+	assert.NoError(t, err)
+
+	_, ok = byID("3")
+	assert.False(t, ok)
+
+	_, ok = byID("4")
+	assert.True(t, ok)
+}
@@ -0,0 +1,320 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewWatcher creates a Watcher with the provided opts
+func NewWatcher(opts ...WatcherOpt) *Watcher {
+	w := &Watcher{
+		debounce: 100 * time.Millisecond,
+		interval: time.Second,
+		logger:   slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Watcher reloads collections when the files they are extracted from change.
+// it uses inotify on linux and falls back to polling the files elsewhere or
+// when inotify is unavailable. bursts of changes, such as a write followed by
+// an atomic rename, are debounced into a single reload. the outcome of the
+// reload is reported through the Stats of the collection
+type Watcher struct {
+	debounce time.Duration
+	interval time.Duration
+	polling  bool
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	watches  []watch
+	notifier notifier
+}
+
+// WatcherOpt is an option for instrumenting a Watcher
+type WatcherOpt func(*Watcher)
+
+// WatchDebounce sets the duration for which a collection's reload is delayed
+// since the last change of its files. the default is 100ms
+func WatchDebounce(d time.Duration) WatcherOpt {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// WatchPolling forces the Watcher to poll the files in the provided interval
+// instead of relying on notifications from the os
+func WatchPolling(interval time.Duration) WatcherOpt {
+	return func(w *Watcher) {
+		w.polling = true
+		w.interval = interval
+	}
+}
+
+// WatchLogger sets the logger the Watcher reports failed reloads to. by
+// default, slog.Default() is used
+func WatchLogger(logger *slog.Logger) WatcherOpt {
+	return func(w *Watcher) {
+		w.logger = logger
+	}
+}
+
+// watch maps a path to the collection that is reloaded when it changes
+type watch struct {
+	path        string
+	dir         bool
+	col         Reloadable
+	fingerprint uint64
+}
+
+// notifier notifies about changes in watched directories
+type notifier interface {
+	// add watches the provided directory, along with its subdirectories if
+	// recursive
+	add(dir string, recursive bool) error
+
+	// events is the channel of the changed paths. an empty path means that
+	// changes might have been missed
+	events() <-chan string
+
+	close() error
+}
+
+// Watch reloads the provided collection whenever the provided file, or any
+// file under the provided directory, changes. the FileExtractor's Path is the
+// natural path to watch for a file-backed collection
+func (w *Watcher) Watch(path string, col Reloadable) (err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	wt := watch{path: path, dir: info.IsDir(), col: col}
+	wt.fingerprint, err = fingerprint(path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.notifier != nil {
+		if err = w.notifier.add(wt.watchedDir(), wt.dir); err != nil {
+			return
+		}
+	}
+
+	w.watches = append(w.watches, wt)
+
+	return
+}
+
+// watchedDir is the directory that is watched for changes of the path. a file
+// is watched through its directory, but not its subdirectories, in order to
+// catch atomic renames over it
+func (wt watch) watchedDir() string {
+	if wt.dir {
+		return wt.path
+	}
+
+	return filepath.Dir(wt.path)
+}
+
+func (wt watch) matches(path string) bool {
+	if path == "" || path == wt.path {
+		return true
+	}
+
+	return wt.dir && strings.HasPrefix(path, wt.path+string(filepath.Separator))
+}
+
+// Run watches the files until the provided ctx is done
+func (w *Watcher) Run(ctx context.Context) (err error) {
+	var events <-chan string
+	if !w.polling {
+		if events, err = w.startNotifier(); err != nil {
+			w.logger.Warn("falling back to polling files", "error", err)
+			err = nil
+		} else {
+			defer w.stopNotifier()
+		}
+	}
+
+	var poll <-chan time.Time
+	if events == nil {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		poll = ticker.C
+	}
+
+	pending := map[Reloadable]time.Time{}
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	schedule := func(changed []Reloadable) {
+		if len(changed) == 0 {
+			return
+		}
+
+		due := time.Now().Add(w.debounce)
+		for _, col := range changed {
+			pending[col] = due
+		}
+
+		timer.Reset(w.debounce)
+	}
+
+	// catch the changes since the paths were registered
+	schedule(w.poll())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path, ok := <-events:
+			if !ok {
+				return fmt.Errorf("file notifications stopped")
+			}
+
+			schedule(w.match(path))
+		case <-poll:
+			schedule(w.poll())
+		case <-timer.C:
+			now := time.Now()
+			next := time.Duration(0)
+
+			for col, due := range pending {
+				if left := due.Sub(now); left > 0 {
+					if next == 0 || left < next {
+						next = left
+					}
+
+					continue
+				}
+
+				delete(pending, col)
+				if _, err := col.Reload(ctx); err != nil {
+					w.logger.Error("failed to reload on file change", "kind", col.Kind(), "error", err)
+				}
+			}
+
+			if next > 0 {
+				timer.Reset(next)
+			}
+		}
+	}
+}
+
+func (w *Watcher) startNotifier() (events <-chan string, err error) {
+	n, err := newNotifier()
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, wt := range w.watches {
+		if err = n.add(wt.watchedDir(), wt.dir); err != nil {
+			_ = n.close()
+			return
+		}
+	}
+
+	w.notifier = n
+
+	return n.events(), nil
+}
+
+func (w *Watcher) stopNotifier() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.notifier != nil {
+		_ = w.notifier.close()
+		w.notifier = nil
+	}
+}
+
+// match returns the collections that are watching the provided path
+func (w *Watcher) match(path string) (res []Reloadable) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, wt := range w.watches {
+		if wt.matches(path) {
+			res = append(res, wt.col)
+		}
+	}
+
+	return
+}
+
+// poll returns the collections whose watched paths have changed since the
+// last poll
+func (w *Watcher) poll() (res []Reloadable) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, wt := range w.watches {
+		fp, err := fingerprint(wt.path)
+		if err != nil {
+			w.logger.Warn("failed to poll file", "path", wt.path, "error", err)
+			continue
+		}
+
+		if fp != wt.fingerprint {
+			w.watches[i].fingerprint = fp
+			res = append(res, wt.col)
+		}
+	}
+
+	return
+}
+
+// fingerprint hashes the names, sizes and modification times of the file, or
+// of all the files under the directory, of the provided path. a missing path
+// has a zero fingerprint
+func fingerprint(path string) (uint64, error) {
+	h := fnv.New64a()
+
+	err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(h, "%s|%d|%d|", path, info.Size(), info.ModTime().UnixNano())
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	return h.Sum64(), err
+}
@@ -0,0 +1,161 @@
+package inventory
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_ATTRIB
+
+// inotify is a notifier that is backed by linux's inotify
+type inotify struct {
+	fd   int
+	file *os.File
+	ch   chan string
+	done chan struct{}
+
+	mu   sync.Mutex
+	dirs map[int]inotifyDir
+}
+
+// inotifyDir is a directory that is watched by inotify. new subdirectories of
+// a recursive directory are watched too
+type inotifyDir struct {
+	path      string
+	recursive bool
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &inotify{
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		ch:   make(chan string, 64),
+		done: make(chan struct{}),
+		dirs: map[int]inotifyDir{},
+	}
+
+	go n.read()
+
+	return n, nil
+}
+
+func (n *inotify) add(dir string, recursive bool) error {
+	if !recursive {
+		return n.watch(dir, false)
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		return n.watch(path, true)
+	})
+}
+
+// watch adds a watch of the provided directory. a directory that is watched
+// both recursively and not is watched recursively
+func (n *inotify) watch(dir string, recursive bool) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+
+	n.mu.Lock()
+	n.dirs[wd] = inotifyDir{dir, recursive || n.dirs[wd].recursive}
+	n.mu.Unlock()
+
+	return nil
+}
+
+func (n *inotify) events() <-chan string {
+	return n.ch
+}
+
+func (n *inotify) close() error {
+	close(n.done)
+
+	return n.file.Close()
+}
+
+// send sends the provided path to the events channel unless the notifier is
+// closed
+func (n *inotify) send(path string) bool {
+	select {
+	case n.ch <- path:
+		return true
+	case <-n.done:
+		return false
+	}
+}
+
+func (n *inotify) read() {
+	defer close(n.ch)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				n.send("")
+			}
+
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				if !n.send("") {
+					return
+				}
+
+				continue
+			}
+
+			n.mu.Lock()
+			dir, ok := n.dirs[int(event.Wd)]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(n.dirs, int(event.Wd))
+			}
+			n.mu.Unlock()
+
+			if !ok {
+				continue
+			}
+
+			path := dir.path
+			if name := strings.TrimRight(string(nameBytes), "\x00"); len(name) > 0 {
+				path = filepath.Join(dir.path, name)
+			}
+
+			if dir.recursive && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && event.Mask&syscall.IN_ISDIR != 0 {
+				_ = n.add(path, true)
+			}
+
+			if !n.send(path) {
+				return
+			}
+		}
+	}
+}
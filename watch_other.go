@@ -0,0 +1,11 @@
+//go:build !linux
+
+package inventory
+
+import (
+	"errors"
+)
+
+func newNotifier() (notifier, error) {
+	return nil, errors.New("file notifications are not supported on this platform")
+}
@@ -0,0 +1,145 @@
+package inventory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	test := func(t *testing.T, opts ...WatcherOpt) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "books.json")
+
+		write := func(content string) {
+			tmp := filepath.Join(dir, ".books.json.tmp")
+			if !assert.NoError(t, os.WriteFile(tmp, []byte(content), 0o644)) {
+				t.FailNow()
+			}
+
+			if !assert.NoError(t, os.Rename(tmp, path)) {
+				t.FailNow()
+			}
+		}
+
+		write(`[{"id": "1", "title": "Dune"}]`)
+
+		col := NewCollection[*book](NewDB(), "books",
+			Source(NewFileExtractor[*book](path).Extract),
+			PrimaryKey("id", func(b *book, val func(string)) { val(b.ID) }),
+		)
+		byID := col.GetBy("id")
+
+		_, err := col.Reload(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		w := NewWatcher(append([]WatcherOpt{
+			WatchDebounce(50 * time.Millisecond),
+			WatchLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		}, opts...)...)
+		if !assert.NoError(t, w.Watch(path, col)) {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- w.Run(ctx) }()
+		defer func() {
+			cancel()
+			assert.NoError(t, <-done)
+		}()
+
+		for i := 0; i < 5; i++ {
+			write(`[{"id": "1", "title": "Dune"}, {"id": "2", "title": "Dune Messiah"}]`)
+		}
+
+		assert.Eventually(t, func() bool {
+			_, ok := byID("2")
+			return ok
+		}, 5*time.Second, 10*time.Millisecond)
+
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, 2, col.Stats().Reloads)
+
+		write(`[{"id": "1", "title": "Dune"`)
+
+		assert.Eventually(t, func() bool {
+			return col.Stats().LastError != ""
+		}, 5*time.Second, 10*time.Millisecond)
+
+		assert.Contains(t, col.Stats().LastError, "books.json: record 1 (line 1): unexpected EOF")
+
+		_, ok := byID("2")
+		assert.True(t, ok)
+	}
+
+	t.Run("notifications", func(t *testing.T) {
+		test(t)
+	})
+
+	t.Run("polling", func(t *testing.T) {
+		test(t, WatchPolling(20*time.Millisecond))
+	})
+}
+
+func TestNotifier(t *testing.T) {
+	n, err := newNotifier()
+	if err != nil {
+		t.Skip(err)
+	}
+	defer n.close()
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if !assert.NoError(t, os.Mkdir(sub, 0o755)) {
+		return
+	}
+
+	// a file is watched through its directory, without the subdirectories
+	if !assert.NoError(t, n.add(dir, false)) {
+		return
+	}
+
+	next := func() string {
+		select {
+		case path := <-n.events():
+			return path
+		case <-time.After(100 * time.Millisecond):
+			return ""
+		}
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "a.json"), nil, 0o644))
+	assert.Equal(t, "", next())
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "new"), 0o755))
+	assert.Equal(t, filepath.Join(dir, "new"), next())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new", "b.json"), nil, 0o644))
+	assert.Equal(t, "", next())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "books.json"), nil, 0o644))
+	assert.Equal(t, filepath.Join(dir, "books.json"), next())
+
+	// a directory is watched along with its subdirectories, including new ones
+	assert.NoError(t, n.add(dir, true))
+	for next() != "" {
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "a.json"), nil, 0o644))
+	assert.Equal(t, filepath.Join(sub, "a.json"), next())
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "newer"), 0o755))
+	assert.Equal(t, filepath.Join(dir, "newer"), next())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "newer", "c.json"), nil, 0o644))
+	assert.Equal(t, filepath.Join(dir, "newer", "c.json"), next())
+}